@@ -1,15 +1,28 @@
 package chess
 
 import (
-	// "fmt"
+	"fmt"
+	"os"
+
 	// "github.com/vincer2040/chess/internal/game"
 	"github.com/labstack/echo/v4"
 	// "github.com/labstack/echo/v4/middleware"
 	"github.com/vincer2040/chess/internal/render"
 	"github.com/vincer2040/chess/internal/routes"
+	"github.com/vincer2040/chess/internal/uci"
 )
 
 func Main() error {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "perft":
+			return perft(os.Args[2:])
+		case "uci":
+			return uci.Run(os.Stdin, os.Stdout)
+		default:
+			return fmt.Errorf("unknown command: %s", os.Args[1])
+		}
+	}
 	// game := game.New("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
 	// legalMoves := game.GetLegalMoves()
 	// fmt.Printf("legalMoves: %v\n", legalMoves)
@@ -22,7 +35,10 @@ func Main() error {
 	e.Static("pieces", "public/pieces")
 
 	e.GET("/", routes.RootGet)
+	e.POST("/new", routes.RootNew)
 	e.GET("/game", routes.GameGet)
+	e.GET("/game/:id", routes.GameRoomGet)
+	e.GET("/game/:id/watch", routes.GameRoomWatch)
 
 	e.Logger.Fatal(e.Start(":8080"))
 	return nil
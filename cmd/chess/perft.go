@@ -0,0 +1,47 @@
+package chess
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vincer2040/chess/internal/game"
+)
+
+// perft runs `chess perft <fen> <depth>`. the fen may be passed
+// as one quoted argument or as its space separated fields
+func perft(args []string) error {
+	if len(args) < 2 {
+		return errors.New("usage: chess perft <fen> <depth>")
+	}
+	fen := strings.Join(args[:len(args)-1], " ")
+	depth, err := strconv.Atoi(args[len(args)-1])
+	if err != nil || depth < 1 {
+		return fmt.Errorf("invalid depth: %s", args[len(args)-1])
+	}
+	g, err := game.Parse(fen)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	divide := g.Divide(depth)
+	elapsed := time.Since(start)
+
+	moves := make([]string, 0, len(divide))
+	for move := range divide {
+		moves = append(moves, move)
+	}
+	sort.Strings(moves)
+	var total uint64
+	for _, move := range moves {
+		fmt.Printf("%s: %d\n", move, divide[move])
+		total += divide[move]
+	}
+	fmt.Printf("\nNodes searched: %d\n", total)
+	fmt.Printf("Time: %v\n", elapsed)
+	return nil
+}
@@ -0,0 +1,225 @@
+package clock
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Mode is what a period's increment means
+type Mode int
+
+const (
+	// Fischer adds the increment after every move
+	Fischer Mode = iota
+	// Bronstein gives back the time a move took, up to the increment
+	Bronstein
+	// Delay waits the increment before the clock starts counting down
+	Delay
+)
+
+// Period is one stage of a time control, such as 40 moves in 90 minutes
+type Period struct {
+	// moves to be made in the period, 0 for the rest of the game
+	Moves     int
+	Time      time.Duration
+	Increment time.Duration
+}
+
+type Control struct {
+	Mode    Mode
+	Periods []Period
+}
+
+// ParseMode parses fischer, bronstein or delay
+func ParseMode(s string) (Mode, error) {
+	switch s {
+	case "fischer":
+		return Fischer, nil
+	case "bronstein":
+		return Bronstein, nil
+	case "delay":
+		return Delay, nil
+	}
+	return Fischer, fmt.Errorf("unknown clock mode: %s", s)
+}
+
+// ParseControl parses periods separated by commas, each written as
+// [moves/]minutes[+seconds]. 5+3 is five minutes with three seconds
+// a move, and 40/90+30,30+30 is ninety minutes for 40 moves then
+// thirty for the rest of the game, with thirty seconds a move
+func ParseControl(s string) (Control, error) {
+	var control Control
+	for _, field := range strings.Split(s, ",") {
+		var p Period
+		if moves, rest, ok := strings.Cut(field, "/"); ok {
+			n, err := strconv.Atoi(moves)
+			if err != nil || n < 1 {
+				return control, fmt.Errorf("invalid number of moves: %s", moves)
+			}
+			p.Moves = n
+			field = rest
+		}
+		minutes, seconds, hasIncrement := strings.Cut(field, "+")
+		m, err := strconv.ParseFloat(minutes, 64)
+		if err != nil || m <= 0 {
+			return control, fmt.Errorf("invalid minutes: %s", minutes)
+		}
+		p.Time = time.Duration(m * float64(time.Minute))
+		if hasIncrement {
+			sec, err := strconv.ParseFloat(seconds, 64)
+			if err != nil || sec < 0 {
+				return control, fmt.Errorf("invalid increment: %s", seconds)
+			}
+			p.Increment = time.Duration(sec * float64(time.Second))
+		}
+		control.Periods = append(control.Periods, p)
+	}
+	for _, p := range control.Periods[:len(control.Periods)-1] {
+		if p.Moves == 0 {
+			return control, errors.New("only the last period can be for the rest of the game")
+		}
+	}
+	return control, nil
+}
+
+// period is the period the nth move of a side is made in, and whether
+// it is the period's last move. a last period with a number of moves
+// repeats for as long as the game goes on
+func (c Control) period(n int) (Period, bool) {
+	for i, p := range c.Periods {
+		if p.Moves == 0 {
+			return p, false
+		}
+		if n <= p.Moves {
+			return p, n == p.Moves
+		}
+		n -= p.Moves
+		if i == len(c.Periods)-1 {
+			n = (n-1)%p.Moves + 1
+			return p, n == p.Moves
+		}
+	}
+	return Period{}, false
+}
+
+// Clock keeps the time of both sides. sides are 'w' and 'b' like the
+// game's side to move, and only the side to move's time runs. the
+// times are passed in so that whoever owns the clock decides what
+// time it is
+type Clock struct {
+	control   Control
+	remaining [2]time.Duration
+	moves     [2]int
+	toMove    byte
+	running   bool
+	turnStart time.Time
+}
+
+// New makes a clock that has not started yet, with toMove to move first
+func New(control Control, toMove byte) *Clock {
+	c := &Clock{control: control, toMove: toMove}
+	first, _ := control.period(1)
+	c.remaining = [2]time.Duration{first.Time, first.Time}
+	return c
+}
+
+func side(color byte) int {
+	if color == 'w' {
+		return 0
+	}
+	return 1
+}
+
+func (c *Clock) Control() Control {
+	return c.control
+}
+
+func (c *Clock) Running() bool {
+	return c.running
+}
+
+func (c *Clock) ToMove() byte {
+	return c.toMove
+}
+
+// charge is how much of the elapsed time a move costs in period p
+func (c *Clock) charge(p Period, elapsed time.Duration) time.Duration {
+	if c.control.Mode == Delay {
+		return max(elapsed-p.Increment, 0)
+	}
+	return elapsed
+}
+
+// Remaining is how much time color has left at now
+func (c *Clock) Remaining(color byte, now time.Time) time.Duration {
+	i := side(color)
+	if !c.running || color != c.toMove {
+		return c.remaining[i]
+	}
+	p, _ := c.control.period(c.moves[i] + 1)
+	return c.remaining[i] - c.charge(p, now.Sub(c.turnStart))
+}
+
+// Flagged reports whether the side to move has run out of time at now
+func (c *Clock) Flagged(now time.Time) bool {
+	return c.running && c.Remaining(c.toMove, now) <= 0
+}
+
+// Deadline is when the side to move will run out of time
+func (c *Clock) Deadline() time.Time {
+	i := side(c.toMove)
+	deadline := c.turnStart.Add(c.remaining[i])
+	if c.control.Mode == Delay {
+		p, _ := c.control.period(c.moves[i] + 1)
+		deadline = deadline.Add(p.Increment)
+	}
+	return deadline
+}
+
+// Press ends the turn of the side to move at now and starts their
+// opponent's time. callers check Flagged first, since a move made
+// after running out of time doesn't count. the first press of a
+// clock that isn't running is free
+func (c *Clock) Press(now time.Time) {
+	i := side(c.toMove)
+	p, last := c.control.period(c.moves[i] + 1)
+	if c.running {
+		elapsed := now.Sub(c.turnStart)
+		c.remaining[i] -= c.charge(p, elapsed)
+		switch c.control.Mode {
+		case Fischer:
+			c.remaining[i] += p.Increment
+			break
+		case Bronstein:
+			c.remaining[i] += min(elapsed, p.Increment)
+			break
+		}
+	}
+	c.moves[i]++
+	if last {
+		next, _ := c.control.period(c.moves[i] + 1)
+		c.remaining[i] += next.Time
+	}
+	if c.toMove == 'w' {
+		c.toMove = 'b'
+	} else {
+		c.toMove = 'w'
+	}
+	c.turnStart = now
+	c.running = true
+}
+
+// Stop charges the side to move for their turn so far and
+// stops the clock, once the game is over
+func (c *Clock) Stop(now time.Time) {
+	if !c.running {
+		return
+	}
+	i := side(c.toMove)
+	p, _ := c.control.period(c.moves[i] + 1)
+	c.remaining[i] = max(c.remaining[i]-c.charge(p, now.Sub(c.turnStart)), 0)
+	c.running = false
+}
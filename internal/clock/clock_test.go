@@ -0,0 +1,128 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+var t0 = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func at(d time.Duration) time.Time {
+	return t0.Add(d)
+}
+
+func TestParseControl(t *testing.T) {
+	c, err := ParseControl("40/90+30,30+30")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []Period{
+		{Moves: 40, Time: 90 * time.Minute, Increment: 30 * time.Second},
+		{Time: 30 * time.Minute, Increment: 30 * time.Second},
+	}
+	if len(c.Periods) != len(expected) {
+		t.Fatalf("expected %d periods, got %d", len(expected), len(c.Periods))
+	}
+	for i, p := range expected {
+		if c.Periods[i] != p {
+			t.Errorf("period %d: expected %+v, got %+v", i, p, c.Periods[i])
+		}
+	}
+	for _, s := range []string{"", "x", "5+x", "0/5", "5,40/90", "-1+0"} {
+		if _, err := ParseControl(s); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
+
+func TestFischer(t *testing.T) {
+	control, _ := ParseControl("5+3")
+	c := New(control, 'w')
+	c.Press(at(0))
+	if c.Remaining('w', at(0)) != 5*time.Minute {
+		t.Errorf("the first move should be free, got %v", c.Remaining('w', at(0)))
+	}
+	c.Press(at(10 * time.Second))
+	if got := c.Remaining('b', at(10*time.Second)); got != 5*time.Minute-7*time.Second {
+		t.Errorf("expected black to have 4m53s, got %v", got)
+	}
+	if got := c.Remaining('w', at(15*time.Second)); got != 5*time.Minute-5*time.Second {
+		t.Errorf("expected white's time to be running, got %v", got)
+	}
+	if c.Flagged(at(5 * time.Minute)) {
+		t.Errorf("white should still have time")
+	}
+	if !c.Flagged(at(5*time.Minute + 10*time.Second)) {
+		t.Errorf("white should have flagged")
+	}
+	if c.Deadline() != at(5*time.Minute+10*time.Second) {
+		t.Errorf("unexpected deadline %v", c.Deadline())
+	}
+}
+
+func TestBronstein(t *testing.T) {
+	control, _ := ParseControl("5+3")
+	control.Mode = Bronstein
+	c := New(control, 'w')
+	c.Press(at(0))
+	c.Press(at(2 * time.Second))
+	if got := c.Remaining('b', at(2*time.Second)); got != 5*time.Minute {
+		t.Errorf("a fast move should cost nothing, got %v", got)
+	}
+	c.Press(at(12 * time.Second))
+	if got := c.Remaining('w', at(12*time.Second)); got != 5*time.Minute-7*time.Second {
+		t.Errorf("expected white to get back three seconds, got %v", got)
+	}
+}
+
+func TestDelay(t *testing.T) {
+	control, _ := ParseControl("5+3")
+	control.Mode = Delay
+	c := New(control, 'w')
+	c.Press(at(0))
+	if got := c.Remaining('b', at(2*time.Second)); got != 5*time.Minute {
+		t.Errorf("the clock shouldn't run during the delay, got %v", got)
+	}
+	c.Press(at(10 * time.Second))
+	if got := c.Remaining('b', at(10*time.Second)); got != 5*time.Minute-7*time.Second {
+		t.Errorf("expected black to be charged seven seconds, got %v", got)
+	}
+	if c.Deadline() != at(10*time.Second+5*time.Minute+3*time.Second) {
+		t.Errorf("the deadline should include the delay, got %v", c.Deadline())
+	}
+}
+
+func TestPeriods(t *testing.T) {
+	control, _ := ParseControl("2/1,1")
+	c := New(control, 'w')
+	now := t0
+	for i := 0; i < 4; i++ {
+		c.Press(now)
+		now = now.Add(10 * time.Second)
+	}
+	// white made two moves in the first period and gets the second,
+	// less ten seconds for each move since the first
+	if got := c.Remaining('w', now); got != 2*time.Minute-20*time.Second {
+		t.Errorf("expected white to have 1m40s, got %v", got)
+	}
+	repeating, _ := ParseControl("1/1")
+	c = New(repeating, 'b')
+	c.Press(t0)
+	c.Press(t0)
+	if got := c.Remaining('b', t0); got != 2*time.Minute {
+		t.Errorf("expected the last period to repeat, got %v", got)
+	}
+}
+
+func TestStop(t *testing.T) {
+	control, _ := ParseControl("1")
+	c := New(control, 'w')
+	c.Press(t0)
+	c.Stop(at(2 * time.Minute))
+	if c.Running() || c.Flagged(at(3*time.Minute)) {
+		t.Errorf("a stopped clock shouldn't run")
+	}
+	if got := c.Remaining('b', at(3*time.Minute)); got != 0 {
+		t.Errorf("expected black to have no time left, got %v", got)
+	}
+}
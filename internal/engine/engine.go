@@ -0,0 +1,155 @@
+package engine
+
+import (
+	"errors"
+	"time"
+
+	"github.com/vincer2040/chess/internal/game"
+)
+
+const (
+	// scores beyond MateThreshold are forced mates,
+	// MateScore less the number of plies to mate
+	MateScore     = 100000
+	MateThreshold = MateScore - maxPly
+	infinity      = MateScore + 1
+
+	maxPly = 128
+	// the deepest a search can go, for searching until stopped
+	MaxDepth = maxPly - 1
+	// how often the clock is looked at
+	checkEvery = 2048
+)
+
+// Limits bounds a search. a zero field means no limit on it,
+// but a search with no limits at all stops at DefaultDepth
+type Limits struct {
+	Depth int
+	Nodes uint64
+	Time  time.Duration
+	// closing Stop ends the search as soon as it can
+	Stop <-chan struct{}
+}
+
+const DefaultDepth = 6
+
+type Result struct {
+	Move  game.Move
+	Score int
+	Depth int
+	Nodes uint64
+	PV    []game.Move
+}
+
+type Engine struct {
+	tt      *table
+	killers [maxPly][2]game.Move
+	history [64][64]int
+	pv      [maxPly][maxPly]game.Move
+	pvLen   [maxPly]int
+
+	limits   Limits
+	deadline time.Time
+	nodes    uint64
+	stopped  bool
+
+	// OnDepth is called after each finished iteration
+	OnDepth func(Result)
+}
+
+// New creates an engine with a transposition table of about ttMB megabytes
+func New(ttMB int) *Engine {
+	return &Engine{tt: newTable(ttMB)}
+}
+
+// Clear forgets everything learned from earlier searches
+func (e *Engine) Clear() {
+	e.tt.clear()
+	e.killers = [maxPly][2]game.Move{}
+	e.history = [64][64]int{}
+}
+
+// Search finds the best move for the side to move. the game
+// is cloned so it isn't changed, even while the search runs
+func (e *Engine) Search(g *game.Game, limits Limits) (Result, error) {
+	if g.Status().IsOver() {
+		return Result{}, errors.New("game is over")
+	}
+	pos := g.Clone()
+	if limits.Depth <= 0 && limits.Nodes == 0 && limits.Time == 0 && limits.Stop == nil {
+		limits.Depth = DefaultDepth
+	}
+	if limits.Depth <= 0 || limits.Depth > MaxDepth {
+		limits.Depth = MaxDepth
+	}
+	e.limits = limits
+	e.deadline = time.Time{}
+	if limits.Time > 0 {
+		e.deadline = time.Now().Add(limits.Time)
+	}
+	e.nodes = 0
+	e.stopped = false
+	e.killers = [maxPly][2]game.Move{}
+	for from := range e.history {
+		for to := range e.history[from] {
+			e.history[from][to] /= 8
+		}
+	}
+
+	moves := pos.Moves()
+	res := Result{Move: moves.At(0)}
+	for depth := 1; depth <= limits.Depth; depth++ {
+		score := e.negamax(&pos, depth, 0, -infinity, infinity)
+		// a cut short iteration can't be trusted, apart from
+		// the first which has to give us some move
+		if e.stopped && depth > 1 {
+			break
+		}
+		if e.pvLen[0] > 0 {
+			res.Move = e.pv[0][0]
+			res.PV = append([]game.Move(nil), e.pv[0][:e.pvLen[0]]...)
+		}
+		res.Score = score
+		res.Depth = depth
+		res.Nodes = e.nodes
+		if e.OnDepth != nil {
+			e.OnDepth(res)
+		}
+		if e.stopped || score > MateThreshold || score < -MateThreshold {
+			break
+		}
+	}
+	res.Nodes = e.nodes
+	return res, nil
+}
+
+// MateIn turns a mate score into the number of moves to mate,
+// negative when the side to move is the one getting mated
+func MateIn(score int) (int, bool) {
+	if score > MateThreshold {
+		return (MateScore - score + 1) / 2, true
+	}
+	if score < -MateThreshold {
+		return -(MateScore + score + 1) / 2, true
+	}
+	return 0, false
+}
+
+func (e *Engine) shouldStop() bool {
+	if e.stopped {
+		return true
+	}
+	if e.limits.Nodes != 0 && e.nodes >= e.limits.Nodes {
+		e.stopped = true
+	} else if e.nodes%checkEvery == 0 {
+		if !e.deadline.IsZero() && time.Now().After(e.deadline) {
+			e.stopped = true
+		}
+		select {
+		case <-e.limits.Stop:
+			e.stopped = true
+		default:
+		}
+	}
+	return e.stopped
+}
@@ -0,0 +1,107 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vincer2040/chess/internal/game"
+)
+
+func TestSearchFindsBestMove(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+		move string
+	}{
+		{
+			name: "back rank mate",
+			fen:  "6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1",
+			move: "a1a8",
+		},
+		{
+			name: "mate in three",
+			fen:  "r1b1kb1r/pppp1ppp/5q2/4n3/3KP3/2N3PN/PPP4P/R1BQ1B1R b kq - 0 1",
+			move: "f8c5",
+		},
+		{
+			name: "hanging queen",
+			fen:  "rnb1kbnr/pppp1ppp/8/4p1q1/3P4/2N5/PPP1PPPP/R1BQKBNR w KQkq - 0 1",
+			move: "c1g5",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := game.New(tt.fen)
+			e := New(16)
+			res, err := e.Search(&g, Limits{Depth: 5})
+			if err != nil {
+				t.Fatalf("search failed: %v", err)
+			}
+			if res.Move.String() != tt.move {
+				t.Errorf("expected %s, got %s (score %d)", tt.move, res.Move, res.Score)
+			}
+			if g.FEN() != tt.fen {
+				t.Errorf("search changed the game, got %s", g.FEN())
+			}
+		})
+	}
+}
+
+func TestSearchReportsMate(t *testing.T) {
+	g := game.New("6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1")
+	res, err := New(16).Search(&g, Limits{Depth: 4})
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if res.Score != MateScore-1 {
+		t.Errorf("expected mate in one ply, got score %d", res.Score)
+	}
+	if len(res.PV) == 0 || res.PV[0] != res.Move {
+		t.Errorf("expected the pv to start with the best move, got %v", res.PV)
+	}
+}
+
+func TestSearchLimits(t *testing.T) {
+	g := game.New(game.STARTING_POSITION)
+	e := New(16)
+
+	res, err := e.Search(&g, Limits{Nodes: 5000})
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if res.Nodes > 5000 {
+		t.Errorf("expected at most 5000 nodes, searched %d", res.Nodes)
+	}
+
+	start := time.Now()
+	res, err = e.Search(&g, Limits{Time: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the search to stop after 100ms, took %s", elapsed)
+	}
+	if res.Depth == 0 {
+		t.Errorf("expected at least one finished iteration")
+	}
+}
+
+func TestSearchGameOver(t *testing.T) {
+	g := game.New("7k/5Q2/6K1/8/8/8/8/8 b - - 0 1")
+	if _, err := New(1).Search(&g, Limits{Depth: 1}); err == nil {
+		t.Errorf("expected an error searching a finished game")
+	}
+}
+
+func TestTableSize(t *testing.T) {
+	if entrySize != 16 {
+		t.Errorf("expected entries to pack into 16 bytes, got %d", entrySize)
+	}
+	for _, mb := range []int{1, 3, 16} {
+		tt := newTable(mb)
+		size := uint64(len(tt.entries)) * entrySize
+		if size > uint64(mb)<<20 || size*2 <= uint64(mb)<<20 {
+			t.Errorf("%dMB: table is %d bytes", mb, size)
+		}
+	}
+}
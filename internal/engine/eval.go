@@ -0,0 +1,14 @@
+package engine
+
+import (
+	"github.com/vincer2040/chess/internal/game"
+)
+
+// evaluate scores the position from the side to move's point of view
+func evaluate(g *game.Game) int {
+	score := game.Evaluate(g)
+	if g.ToMove() == 'b' {
+		return -score
+	}
+	return score
+}
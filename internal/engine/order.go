@@ -0,0 +1,76 @@
+package engine
+
+import (
+	"github.com/vincer2040/chess/internal/game"
+)
+
+const (
+	ttMoveScore    = 1 << 30
+	captureScore   = 1 << 20
+	promotionScore = 1 << 19
+	killerScore    = 1 << 18
+)
+
+// scoreMoves ranks moves so the ones most likely to cause a cutoff
+// are tried first: the table's best move, captures by most valuable
+// victim then least valuable attacker, killers, then history
+func (e *Engine) scoreMoves(g *game.Game, moves *game.MoveList, scores *[256]int, ttMove game.Move, ply int) {
+	for i := 0; i < moves.Len(); i++ {
+		m := moves.At(i)
+		var score int
+		switch {
+		case m == ttMove:
+			score = ttMoveScore
+			break
+		case m.IsCapture():
+			var victim game.Piece = game.Pawn
+			if !m.IsEnPassant() {
+				victim = g.PieceOn(m.To()) & game.PIECEMASK
+			}
+			attacker := g.PieceOn(m.From()) & game.PIECEMASK
+			score = captureScore + int(victim)*10 - int(attacker)
+			if m.IsPromotion() {
+				score += int(m.PromoteTo())
+			}
+			break
+		case m.IsPromotion():
+			score = promotionScore + int(m.PromoteTo())
+			break
+		case m == e.killers[ply][0]:
+			score = killerScore + 1
+			break
+		case m == e.killers[ply][1]:
+			score = killerScore
+			break
+		default:
+			score = e.history[m.From()][m.To()]
+			if score >= killerScore {
+				score = killerScore - 1
+			}
+			break
+		}
+		scores[i] = score
+	}
+}
+
+// pickMove brings the best scoring move that is left to i,
+// which is cheaper than sorting when a cutoff comes early
+func pickMove(moves *game.MoveList, scores *[256]int, i int) {
+	best := i
+	for j := i + 1; j < moves.Len(); j++ {
+		if scores[j] > scores[best] {
+			best = j
+		}
+	}
+	if best != i {
+		moves.Swap(i, best)
+		scores[i], scores[best] = scores[best], scores[i]
+	}
+}
+
+func (e *Engine) storeKiller(ply int, m game.Move) {
+	if e.killers[ply][0] != m {
+		e.killers[ply][1] = e.killers[ply][0]
+		e.killers[ply][0] = m
+	}
+}
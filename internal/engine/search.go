@@ -0,0 +1,187 @@
+package engine
+
+import (
+	"github.com/vincer2040/chess/internal/game"
+)
+
+func (e *Engine) negamax(g *game.Game, depth, ply, alpha, beta int) int {
+	e.pvLen[ply] = 0
+	if ply > 0 {
+		if e.shouldStop() {
+			return 0
+		}
+		if score, over := terminal(g, ply); over {
+			return score
+		}
+		// a repeated position or one the fifty move rule
+		// lets either side claim is as good as a draw
+		if g.Repetitions() >= 2 || g.HalfmoveClock() >= 100 {
+			return 0
+		}
+	}
+	inCheck := g.InCheck()
+	if inCheck && ply < maxPly-1 {
+		depth++
+	}
+	if depth <= 0 || ply >= maxPly-1 {
+		return e.quiesce(g, ply, alpha, beta)
+	}
+	e.nodes++
+
+	var ttMove game.Move
+	entry, ok := e.tt.probe(g.Hash())
+	if ok {
+		ttMove = entry.move
+		if ply > 0 && int(entry.depth) >= depth {
+			score := fromTT(int(entry.score), ply)
+			switch entry.bound {
+			case exact:
+				return score
+			case lower:
+				if score >= beta {
+					return score
+				}
+				break
+			case upper:
+				if score <= alpha {
+					return score
+				}
+				break
+			}
+		}
+	}
+
+	moves := g.Moves()
+	var scores [256]int
+	e.scoreMoves(g, &moves, &scores, ttMove, ply)
+
+	origAlpha := alpha
+	best := -infinity
+	var bestMove game.Move
+	for i := 0; i < moves.Len(); i++ {
+		pickMove(&moves, &scores, i)
+		m := moves.At(i)
+		g.Play(m)
+		score := -e.negamax(g, depth-1, ply+1, -beta, -alpha)
+		g.UnmakeMove()
+		if e.stopped {
+			return 0
+		}
+		if score > best {
+			best = score
+			bestMove = m
+		}
+		if score > alpha {
+			alpha = score
+			e.updatePV(ply, m)
+		}
+		if alpha >= beta {
+			if !m.IsCapture() && !m.IsPromotion() {
+				e.storeKiller(ply, m)
+				e.history[m.From()][m.To()] += depth * depth
+			}
+			break
+		}
+	}
+
+	bound := exact
+	if best <= origAlpha {
+		bound = upper
+	} else if best >= beta {
+		bound = lower
+	}
+	e.tt.store(g.Hash(), bestMove, toTT(best, ply), depth, bound)
+	return best
+}
+
+// quiesce only looks at captures and promotions so the
+// position is quiet before it gets evaluated
+func (e *Engine) quiesce(g *game.Game, ply, alpha, beta int) int {
+	e.pvLen[ply] = 0
+	if e.shouldStop() {
+		return 0
+	}
+	e.nodes++
+	if score, over := terminal(g, ply); over {
+		return score
+	}
+	inCheck := g.InCheck()
+	if !inCheck {
+		standPat := evaluate(g)
+		if standPat >= beta || ply >= maxPly-1 {
+			return standPat
+		}
+		if standPat > alpha {
+			alpha = standPat
+		}
+	} else if ply >= maxPly-1 {
+		return evaluate(g)
+	}
+
+	moves := g.Moves()
+	var scores [256]int
+	e.scoreMoves(g, &moves, &scores, 0, ply)
+	for i := 0; i < moves.Len(); i++ {
+		pickMove(&moves, &scores, i)
+		m := moves.At(i)
+		// when in check every move has to be looked at
+		if !inCheck && !m.IsCapture() && m.PromoteTo() != game.Queen {
+			continue
+		}
+		g.Play(m)
+		score := -e.quiesce(g, ply+1, -beta, -alpha)
+		g.UnmakeMove()
+		if e.stopped {
+			return 0
+		}
+		if score > alpha {
+			alpha = score
+			e.updatePV(ply, m)
+		}
+		if alpha >= beta {
+			break
+		}
+	}
+	return alpha
+}
+
+// terminal scores a position where the game has ended
+func terminal(g *game.Game, ply int) (int, bool) {
+	switch g.Status() {
+	case game.Ongoing:
+		return 0, false
+	case game.WhiteWinsByCheckmate, game.BlackWinsByCheckmate:
+		// the side to move is the one that got mated
+		return -MateScore + ply, true
+	}
+	return 0, true
+}
+
+func (e *Engine) updatePV(ply int, m game.Move) {
+	e.pv[ply][0] = m
+	n := e.pvLen[ply+1]
+	copy(e.pv[ply][1:], e.pv[ply+1][:n])
+	e.pvLen[ply] = n + 1
+}
+
+// mate scores are kept relative to the node in the table,
+// and relative to the root everywhere else
+func toTT(score, ply int) int {
+	if score > MateThreshold {
+		return score + ply
+	}
+	if score < -MateThreshold {
+		return score - ply
+	}
+	return score
+}
+
+func fromTT(score, ply int) int {
+	if score > MateThreshold {
+		return score - ply
+	}
+	if score < -MateThreshold {
+		return score + ply
+	}
+	return score
+}
@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"unsafe"
+
+	"github.com/vincer2040/chess/internal/game"
+)
+
+type bound uint8
+
+const (
+	exact bound = iota
+	// the score is at least this much
+	lower
+	// the score is at most this much
+	upper
+)
+
+// entry is laid out largest field first so it packs into 16 bytes
+type entry struct {
+	key   uint64
+	score int32
+	move  game.Move
+	depth int8
+	bound bound
+}
+
+// table is the transposition table, indexed by zobrist hash.
+// a newer entry always replaces what was in its slot
+type table struct {
+	entries []entry
+	mask    uint64
+}
+
+const entrySize = uint64(unsafe.Sizeof(entry{}))
+
+func newTable(mb int) *table {
+	if mb < 1 {
+		mb = 1
+	}
+	// round down to a power of two so the index is a mask
+	n := uint64(1)
+	for n*2*entrySize <= uint64(mb)<<20 {
+		n *= 2
+	}
+	return &table{entries: make([]entry, n), mask: n - 1}
+}
+
+func (t *table) probe(key uint64) (entry, bool) {
+	e := t.entries[key&t.mask]
+	return e, e.key == key
+}
+
+func (t *table) store(key uint64, move game.Move, score, depth int, b bound) {
+	t.entries[key&t.mask] = entry{
+		key:   key,
+		move:  move,
+		score: int32(score),
+		depth: int8(depth),
+		bound: b,
+	}
+}
+
+func (t *table) clear() {
+	for i := range t.entries {
+		t.entries[i] = entry{}
+	}
+}
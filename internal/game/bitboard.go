@@ -0,0 +1,246 @@
+package game
+
+import (
+	"math/bits"
+)
+
+// a bitboard has bit n set for board index n,
+// so a8 is the lowest bit and h1 the highest
+type bitboards struct {
+	pieces   [2][King + 1]uint64
+	colors   [2]uint64
+	occupied uint64
+}
+
+type magic struct {
+	mask    uint64
+	magic   uint64
+	shift   uint
+	attacks []uint64
+}
+
+var (
+	knightAttacks [64]uint64
+	kingAttacks   [64]uint64
+	// squares attacked by a pawn of the given color index
+	pawnAttacks [2][64]uint64
+	// squares strictly between two squares on a shared line
+	between [64][64]uint64
+
+	// a8 is a light square
+	lightSquares uint64 = 0xaa55aa55aa55aa55
+
+	rookMagics   [64]magic
+	bishopMagics [64]magic
+
+	rookDirections   = [][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+	bishopDirections = [][2]int{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+)
+
+func init() {
+	for sq := 0; sq < 64; sq++ {
+		rank := getRankForIdx(sq)
+		file := getFileForIdx(sq)
+		for _, offset := range knightOffsets {
+			if to, ok := idxForRankAndFile(rank+offset[0], file+offset[1]); ok {
+				knightAttacks[sq] |= bit(to)
+			}
+		}
+		for dr := -1; dr <= 1; dr++ {
+			for df := -1; df <= 1; df++ {
+				if dr == 0 && df == 0 {
+					continue
+				}
+				if to, ok := idxForRankAndFile(rank+dr, file+df); ok {
+					kingAttacks[sq] |= bit(to)
+				}
+			}
+		}
+		for _, df := range []int{-1, 1} {
+			if to, ok := idxForRankAndFile(rank-1, file+df); ok {
+				pawnAttacks[colorIndex(White)][sq] |= bit(to)
+			}
+			if to, ok := idxForRankAndFile(rank+1, file+df); ok {
+				pawnAttacks[colorIndex(Black)][sq] |= bit(to)
+			}
+		}
+	}
+
+	for from := 0; from < 64; from++ {
+		for _, dirs := range [][][2]int{rookDirections, bishopDirections} {
+			for _, dir := range dirs {
+				var ray uint64
+				rank := getRankForIdx(from) + dir[0]
+				file := getFileForIdx(from) + dir[1]
+				for {
+					to, ok := idxForRankAndFile(rank, file)
+					if !ok {
+						break
+					}
+					between[from][to] = ray
+					ray |= bit(to)
+					rank += dir[0]
+					file += dir[1]
+				}
+			}
+		}
+	}
+
+	var seed uint64 = 0x2545f4914f6cdd1d
+	initMagics(&rookMagics, rookDirections, &seed)
+	initMagics(&bishopMagics, bishopDirections, &seed)
+}
+
+// initMagics searches for magic numbers that perfectly hash every
+// blocker arrangement of a square into its attack set
+func initMagics(magics *[64]magic, dirs [][2]int, seed *uint64) {
+	random := func() uint64 {
+		*seed ^= *seed >> 12
+		*seed ^= *seed << 25
+		*seed ^= *seed >> 27
+		return *seed * 0x2545f4914f6cdd1d
+	}
+	for sq := 0; sq < 64; sq++ {
+		m := &magics[sq]
+		m.mask = slidingMask(sq, dirs)
+		n := bits.OnesCount64(m.mask)
+		m.shift = uint(64 - n)
+		size := 1 << n
+
+		occupancies := make([]uint64, 0, size)
+		references := make([]uint64, 0, size)
+		// enumerate every subset of the mask
+		var subset uint64
+		for {
+			occupancies = append(occupancies, subset)
+			references = append(references, slidingAttacks(sq, subset, dirs))
+			subset = (subset - m.mask) & m.mask
+			if subset == 0 {
+				break
+			}
+		}
+
+		m.attacks = make([]uint64, size)
+		used := make([]int, size)
+		for attempt := 1; ; attempt++ {
+			candidate := random() & random() & random()
+			if bits.OnesCount64((m.mask*candidate)>>56) < 6 {
+				continue
+			}
+			ok := true
+			for i, occ := range occupancies {
+				idx := (occ * candidate) >> m.shift
+				if used[idx] != attempt {
+					used[idx] = attempt
+					m.attacks[idx] = references[i]
+				} else if m.attacks[idx] != references[i] {
+					ok = false
+					break
+				}
+			}
+			if ok {
+				m.magic = candidate
+				break
+			}
+		}
+	}
+}
+
+// slidingMask is every square a slider could be blocked on, which
+// leaves out the last square in each direction
+func slidingMask(sq int, dirs [][2]int) uint64 {
+	var res uint64
+	for _, dir := range dirs {
+		rank := getRankForIdx(sq) + dir[0]
+		file := getFileForIdx(sq) + dir[1]
+		for {
+			to, ok := idxForRankAndFile(rank, file)
+			if !ok {
+				break
+			}
+			if _, ok := idxForRankAndFile(rank+dir[0], file+dir[1]); !ok {
+				break
+			}
+			res |= bit(to)
+			rank += dir[0]
+			file += dir[1]
+		}
+	}
+	return res
+}
+
+func slidingAttacks(sq int, occupied uint64, dirs [][2]int) uint64 {
+	var res uint64
+	for _, dir := range dirs {
+		rank := getRankForIdx(sq) + dir[0]
+		file := getFileForIdx(sq) + dir[1]
+		for {
+			to, ok := idxForRankAndFile(rank, file)
+			if !ok {
+				break
+			}
+			res |= bit(to)
+			if occupied&bit(to) != 0 {
+				break
+			}
+			rank += dir[0]
+			file += dir[1]
+		}
+	}
+	return res
+}
+
+func rookAttacks(sq int, occupied uint64) uint64 {
+	m := &rookMagics[sq]
+	return m.attacks[((occupied&m.mask)*m.magic)>>m.shift]
+}
+
+func bishopAttacks(sq int, occupied uint64) uint64 {
+	m := &bishopMagics[sq]
+	return m.attacks[((occupied&m.mask)*m.magic)>>m.shift]
+}
+
+func bit(sq int) uint64 {
+	return 1 << uint(sq)
+}
+
+// popLSB removes the lowest set bit and returns its index
+func popLSB(b *uint64) int {
+	sq := bits.TrailingZeros64(*b)
+	*b &= *b - 1
+	return sq
+}
+
+func colorIndex(color Piece) int {
+	if color == White {
+		return 0
+	}
+	return 1
+}
+
+func (bb *bitboards) put(sq int, piece Piece) {
+	c := colorIndex(piece & COLORMASK)
+	b := bit(sq)
+	bb.pieces[c][piece&PIECEMASK] |= b
+	bb.colors[c] |= b
+	bb.occupied |= b
+}
+
+func (bb *bitboards) remove(sq int, piece Piece) {
+	c := colorIndex(piece & COLORMASK)
+	b := ^bit(sq)
+	bb.pieces[c][piece&PIECEMASK] &= b
+	bb.colors[c] &= b
+	bb.occupied &= b
+}
+
+// attackersTo finds the pieces of color index c that attack sq,
+// treating occupied as the squares that block sliders
+func (bb *bitboards) attackersTo(sq int, c int, occupied uint64) uint64 {
+	pieces := &bb.pieces[c]
+	return (pawnAttacks[1-c][sq] & pieces[Pawn]) |
+		(knightAttacks[sq] & pieces[Knight]) |
+		(kingAttacks[sq] & pieces[King]) |
+		(bishopAttacks(sq, occupied) & (pieces[Bishop] | pieces[Queen])) |
+		(rookAttacks(sq, occupied) & (pieces[Rook] | pieces[Queen]))
+}
@@ -18,32 +18,75 @@ var BOARD_IDXS = [8][8]int{
 	[8]int{56, 57, 58, 59, 60, 61, 62, 63},
 }
 
-type Board []Piece
+type Board [64]Piece
 
-func newBoard(pos string) Board {
+func parseBoard(pos string) (Board, error) {
 	var res Board
-	b := strings.Split(pos, "/")
-	for _, rank := range b {
-		for _, ch := range rank {
-			if util.IsDigit(byte(ch)) {
-				skip := util.ByteToInt(byte(ch))
-				for i := 0; i < skip; i++ {
-					res = append(res, newPiece(' '))
+	idx := 0
+	ranks := strings.Split(pos, "/")
+	if len(ranks) != 8 {
+		return Board{}, fmt.Errorf("expected 8 ranks in piece placement, got %d", len(ranks))
+	}
+	for i, rank := range ranks {
+		squares := 0
+		lastWasDigit := false
+		for _, ch := range []byte(rank) {
+			if util.IsDigit(ch) {
+				skip := util.ByteToInt(ch)
+				if skip == 0 || skip > 8 || lastWasDigit {
+					return Board{}, fmt.Errorf("invalid empty square count in rank %d: %s", 8-i, rank)
+				}
+				if squares+skip > 8 {
+					return Board{}, fmt.Errorf("rank %d has more than 8 squares: %s", 8-i, rank)
 				}
+				idx += skip
+				squares += skip
+				lastWasDigit = true
 				continue
 			}
-			res = append(res, newPiece(byte(ch)))
+			piece, ok := pieceFromByte(ch)
+			if !ok || piece == None {
+				return Board{}, fmt.Errorf("unknown piece %q in rank %d", ch, 8-i)
+			}
+			if squares == 8 {
+				return Board{}, fmt.Errorf("rank %d has more than 8 squares: %s", 8-i, rank)
+			}
+			res[idx] = piece
+			idx++
+			squares++
+			lastWasDigit = false
+		}
+		if squares != 8 {
+			return Board{}, fmt.Errorf("rank %d has %d squares, expected 8: %s", 8-i, squares, rank)
 		}
 	}
-	return res
+	return res, nil
 }
 
-func (b Board) copy() Board {
-    var c Board
-    for _, x := range b {
-        c = append(c, x)
-    }
-    return c
+func (b Board) fen() string {
+	var sb strings.Builder
+	for rank := 0; rank < 8; rank++ {
+		empty := 0
+		for file := 0; file < 8; file++ {
+			piece := b[BOARD_IDXS[rank][file]]
+			if piece == None {
+				empty++
+				continue
+			}
+			if empty != 0 {
+				sb.WriteByte(byte('0' + empty))
+				empty = 0
+			}
+			sb.WriteByte(piece.GetPieceByte())
+		}
+		if empty != 0 {
+			sb.WriteByte(byte('0' + empty))
+		}
+		if rank != 7 {
+			sb.WriteByte('/')
+		}
+	}
+	return sb.String()
 }
 
 func (b Board) print() {
@@ -79,6 +122,13 @@ func getMaxIdxForRank(rank int) int {
 	return BOARD_IDXS[rank][7]
 }
 
+func idxForRankAndFile(rank, file int) (int, bool) {
+	if rank < 0 || rank > 7 || file < 0 || file > 7 {
+		return -1, false
+	}
+	return (rank * 8) + file, true
+}
+
 func getRankForIdx(idx int) int {
 	return idx / 8
 }
@@ -86,3 +136,21 @@ func getRankForIdx(idx int) int {
 func getFileForIdx(idx int) int {
 	return idx % 8
 }
+
+func squareToIdx(square string) (int, bool) {
+	if len(square) != 2 {
+		return -1, false
+	}
+	file := square[0]
+	rank := square[1]
+	if file < 'a' || file > 'h' || rank < '1' || rank > '8' {
+		return -1, false
+	}
+	return BOARD_IDXS['8'-rank][file-'a'], true
+}
+
+func idxToSquare(idx int) string {
+	file := byte('a' + getFileForIdx(idx))
+	rank := byte('8' - getRankForIdx(idx))
+	return string([]byte{file, rank})
+}
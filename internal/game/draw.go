@@ -0,0 +1,138 @@
+package game
+
+import (
+	"errors"
+	"math/bits"
+)
+
+func (g *Game) CanClaimDraw() bool {
+	return g.Repetitions() >= 3 || g.halfmoveClock >= 100
+}
+
+// ClaimDraw ends the game when the side to move is entitled to a
+// draw by threefold repetition or the fifty move rule
+func (g *Game) ClaimDraw() error {
+	if g.status.IsOver() {
+		return errors.New("game is over")
+	}
+	if g.Repetitions() >= 3 {
+		g.status = DrawByThreefoldRepetition
+		return nil
+	}
+	if g.halfmoveClock >= 100 {
+		g.status = DrawByFiftyMoveRule
+		return nil
+	}
+	return errors.New("no draw to claim")
+}
+
+// TimeOut ends the game with the side to move out of time. they
+// lose, unless their opponent doesn't have the material to mate
+func (g *Game) TimeOut() error {
+	if g.status.IsOver() {
+		return errors.New("game is over")
+	}
+	if !g.hasMatingMaterial(colorIndex(g.colorToMove()) ^ 1) {
+		g.status = DrawByTimeoutVsInsufficientMaterial
+		return nil
+	}
+	if g.toMove == 'w' {
+		g.status = BlackWinsOnTime
+	} else {
+		g.status = WhiteWinsOnTime
+	}
+	return nil
+}
+
+// Repetitions counts how many times the current position has been
+// reached. only positions since the last capture or pawn move
+// can be the same, so we don't need to look any further back
+func (g *Game) Repetitions() int {
+	n := len(g.positions)
+	if n == 0 {
+		return 0
+	}
+	current := g.positions[n-1]
+	count := 0
+	for i := n - 1; i >= 0 && i >= n-1-g.halfmoveClock; i-- {
+		if g.positions[i] == current {
+			count++
+		}
+	}
+	return count
+}
+
+// repetitionKey identifies a position for repetition. the hash
+// includes the en passant file whenever a pawn has just pushed
+// two squares, but for repetition it only counts when the
+// capture can actually be made
+func (g *Game) repetitionKey() uint64 {
+	key := g.hash
+	if g.enPassant != -1 && !g.canCaptureEnPassant() {
+		key ^= zobristEnPassant[getFileForIdx(g.enPassant)]
+	}
+	return key
+}
+
+func (g *Game) canCaptureEnPassant() bool {
+	if g.enPassant == -1 {
+		return false
+	}
+	for _, m := range g.moves.moves[:g.moves.n] {
+		if m.IsEnPassant() {
+			return true
+		}
+	}
+	return false
+}
+
+// isDeadPosition reports whether neither side can possibly checkmate:
+// bare kings, a single minor piece, or only bishops that all
+// stand on squares of the same color
+func (g *Game) isDeadPosition() bool {
+	bb := &g.bitboards
+	var knights, bishops uint64
+	for c := 0; c < 2; c++ {
+		if bb.pieces[c][Pawn]|bb.pieces[c][Rook]|bb.pieces[c][Queen] != 0 {
+			return false
+		}
+		knights |= bb.pieces[c][Knight]
+		bishops |= bb.pieces[c][Bishop]
+	}
+	if bits.OnesCount64(knights|bishops) <= 1 {
+		return true
+	}
+	return knights == 0 && (bishops&lightSquares == 0 || bishops&^lightSquares == 0)
+}
+
+// hasMatingMaterial reports whether the side c could still mate,
+// even if only with the other side's help. a lone king never can,
+// and neither can a lone minor piece against a lone king. anything
+// else depends on whether the other side has pieces that could
+// block in their own king
+func (g *Game) hasMatingMaterial(c int) bool {
+	bb := &g.bitboards
+	them := c ^ 1
+	if bb.pieces[c][Pawn]|bb.pieces[c][Rook]|bb.pieces[c][Queen] != 0 {
+		return true
+	}
+	knights := bb.pieces[c][Knight]
+	bishops := bb.pieces[c][Bishop]
+	if knights != 0 {
+		if bits.OnesCount64(knights|bishops) > 1 {
+			return true
+		}
+		// a queen can always get out of the way of a lone knight
+		return bb.pieces[them][Pawn]|bb.pieces[them][Knight]|bb.pieces[them][Bishop]|bb.pieces[them][Rook] != 0
+	}
+	if bishops != 0 {
+		// bishops that all stand on one color need a pawn or a
+		// knight to block the king on a square of the other color
+		all := bishops | bb.pieces[them][Bishop]
+		if all&lightSquares != 0 && all&^lightSquares != 0 {
+			return true
+		}
+		return bb.pieces[them][Pawn]|bb.pieces[them][Knight] != 0
+	}
+	return false
+}
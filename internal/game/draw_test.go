@@ -0,0 +1,207 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/vincer2040/chess/internal/types"
+)
+
+func TestTimeOut(t *testing.T) {
+	tests := []struct {
+		name     string
+		fen      string
+		expected Status
+	}{
+		{"white flags", "4k3/8/8/8/8/8/4P3/4K2r w - - 0 1", BlackWinsOnTime},
+		{"black flags", "4k3/8/8/8/8/8/4P3/4K3 b - - 0 1", WhiteWinsOnTime},
+		{"lone king can't win", "4k3/8/8/8/8/8/4P3/4K3 w - - 0 1", DrawByTimeoutVsInsufficientMaterial},
+		{"knight can win against a pawn", "4k3/8/8/8/8/5n2/4P3/4K3 w - - 0 1", BlackWinsOnTime},
+		{"knight can win against a rook", "4k3/8/8/8/8/5n2/8/4K2R w - - 0 1", BlackWinsOnTime},
+		{"knight can't win against a queen", "4k3/8/8/8/8/5n2/8/4K2Q w - - 0 1", DrawByTimeoutVsInsufficientMaterial},
+		{"bishop can win against a pawn", "4k3/8/8/8/8/5b2/4P3/4K3 w - - 0 1", BlackWinsOnTime},
+		{"bishop can win against an opposite colored bishop", "4k3/8/8/8/8/5b2/8/4K1B1 w - - 0 1", BlackWinsOnTime},
+		{"bishop can't win against a rook", "4k3/8/8/8/8/5b2/8/4K2R w - - 0 1", DrawByTimeoutVsInsufficientMaterial},
+		{"two bishops can win", "4k3/8/8/8/8/5bb1/4P3/4K3 w - - 0 1", BlackWinsOnTime},
+		{"bishops on one color can't win against a rook", "4k3/8/8/8/8/5b2/6b1/4K2R w - - 0 1", DrawByTimeoutVsInsufficientMaterial},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New(tt.fen)
+			if err := g.TimeOut(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if g.Status() != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, g.Status())
+			}
+			if g.TimeOut() == nil {
+				t.Errorf("expected an error timing out a finished game")
+			}
+		})
+	}
+}
+
+// playMoves plays each move in san or uci notation
+func playMoves(t *testing.T, g *Game, moves ...string) {
+	for _, m := range moves {
+		data, err := g.ParseMove(m)
+		if err != nil {
+			t.Fatalf("%s: %v", m, err)
+		}
+		switch data.Type {
+		case types.MoveType:
+			move := data.Data.(types.Move)
+			err = g.MakeMove(&move)
+			break
+		case types.PromotionType:
+			promotion := data.Data.(types.Promotion)
+			err = g.MakePromotion(&promotion)
+			break
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", m, err)
+		}
+	}
+}
+
+var knightShuffle = []string{"Nf3", "Nf6", "Ng1", "Ng8"}
+
+func TestThreefoldRepetition(t *testing.T) {
+	g := New(STARTING_POSITION)
+	playMoves(t, &g, knightShuffle...)
+	if g.CanClaimDraw() || g.ClaimDraw() == nil {
+		t.Fatalf("a position seen twice can't be claimed")
+	}
+	playMoves(t, &g, knightShuffle...)
+	if g.Repetitions() != 3 {
+		t.Fatalf("expected 3 repetitions, got %d", g.Repetitions())
+	}
+	if g.Status() != Ongoing {
+		t.Errorf("threefold repetition has to be claimed, got %s", g.Status())
+	}
+	if !g.CanClaimDraw() {
+		t.Fatalf("expected a draw to be claimable")
+	}
+	if err := g.ClaimDraw(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if g.Status() != DrawByThreefoldRepetition {
+		t.Errorf("expected %s, got %s", DrawByThreefoldRepetition, g.Status())
+	}
+}
+
+func TestFivefoldRepetition(t *testing.T) {
+	g := New(STARTING_POSITION)
+	for i := 0; i < 3; i++ {
+		playMoves(t, &g, knightShuffle...)
+	}
+	if g.Status() != Ongoing {
+		t.Fatalf("expected the game to go on after four repetitions, got %s", g.Status())
+	}
+	playMoves(t, &g, knightShuffle...)
+	if g.Status() != DrawByFivefoldRepetition {
+		t.Errorf("expected %s, got %s", DrawByFivefoldRepetition, g.Status())
+	}
+}
+
+func TestFiftyMoveRule(t *testing.T) {
+	g := New("4k3/8/8/8/8/8/8/R3K3 w - - 98 80")
+	playMoves(t, &g, "Ra2")
+	if g.CanClaimDraw() {
+		t.Fatalf("99 half moves can't be claimed")
+	}
+	playMoves(t, &g, "Kd7")
+	if !g.CanClaimDraw() {
+		t.Fatalf("expected a draw to be claimable")
+	}
+	if err := g.ClaimDraw(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if g.Status() != DrawByFiftyMoveRule {
+		t.Errorf("expected %s, got %s", DrawByFiftyMoveRule, g.Status())
+	}
+}
+
+func TestSeventyFiveMoveRule(t *testing.T) {
+	g := New("4k3/8/8/8/8/8/8/R3K3 w - - 148 100")
+	playMoves(t, &g, "Ra2")
+	if g.Status() != Ongoing {
+		t.Fatalf("expected the game to go on after 149 half moves, got %s", g.Status())
+	}
+	playMoves(t, &g, "Kd7")
+	if g.Status() != DrawBySeventyFiveMoveRule {
+		t.Errorf("expected %s, got %s", DrawBySeventyFiveMoveRule, g.Status())
+	}
+
+	// checkmate on the last move still wins
+	g = New("6k1/5ppp/8/8/8/8/8/R5K1 w - - 149 100")
+	playMoves(t, &g, "Ra8")
+	if g.Status() != WhiteWinsByCheckmate {
+		t.Errorf("expected %s, got %s", WhiteWinsByCheckmate, g.Status())
+	}
+}
+
+func TestRepetitionEnPassant(t *testing.T) {
+	kingShuffle := []string{"Kd8", "Kd1", "Ke8", "Ke1"}
+
+	// after e4 black can take en passant, which it can't once the
+	// kings have come back, so that isn't the same position
+	g := New("4k3/8/8/8/5p2/8/4P3/4K3 w - - 0 1")
+	playMoves(t, &g, "e4")
+	playMoves(t, &g, kingShuffle...)
+	if g.Repetitions() != 1 {
+		t.Errorf("expected the en passant right to make the positions differ, got %d repetitions", g.Repetitions())
+	}
+	playMoves(t, &g, kingShuffle...)
+	if g.Repetitions() != 2 {
+		t.Errorf("expected 2 repetitions, got %d", g.Repetitions())
+	}
+
+	// without a pawn to take en passant the square doesn't count
+	g = New("4k3/8/8/8/8/8/4P3/4K3 w - - 0 1")
+	playMoves(t, &g, "e4")
+	playMoves(t, &g, kingShuffle...)
+	if g.Repetitions() != 2 {
+		t.Errorf("expected an uncapturable en passant square to be ignored, got %d repetitions", g.Repetitions())
+	}
+}
+
+func TestDeadPosition(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+		dead bool
+	}{
+		{"king against king", "4k3/8/8/8/8/8/8/4K3 w - - 0 1", true},
+		{"king and knight against king", "4k3/8/8/8/8/8/8/3NK3 w - - 0 1", true},
+		{"king and bishop against king", "4k3/8/8/8/8/8/8/3BK3 w - - 0 1", true},
+		{"bishops on the same color", "4kb2/8/8/8/8/8/8/2B1K3 w - - 0 1", true},
+		{"several bishops on the same color", "4kb2/8/8/8/8/8/8/B1B1K3 w - - 0 1", true},
+		{"bishops on opposite colors", "4kb2/8/8/8/8/8/8/3BK3 w - - 0 1", false},
+		{"knight against knight", "4kn2/8/8/8/8/8/8/3NK3 w - - 0 1", false},
+		{"two knights", "4k3/8/8/8/8/8/8/2NNK3 w - - 0 1", false},
+		{"a pawn", "4k3/8/8/8/8/8/4P3/4K3 w - - 0 1", false},
+		{"a rook", "4k3/8/8/8/8/8/8/R3K3 w - - 0 1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New(tt.fen)
+			if g.isDeadPosition() != tt.dead {
+				t.Errorf("expected dead to be %v", tt.dead)
+			}
+			expected := Ongoing
+			if tt.dead {
+				expected = DrawByInsufficientMaterial
+			}
+			if g.Status() != expected {
+				t.Errorf("expected %s, got %s", expected, g.Status())
+			}
+		})
+	}
+
+	// a capture leaving bare kings ends the game
+	g := New("4k3/8/8/8/8/8/3p4/4K3 w - - 0 1")
+	playMoves(t, &g, "Kxd2")
+	if g.Status() != DrawByInsufficientMaterial {
+		t.Errorf("expected %s, got %s", DrawByInsufficientMaterial, g.Status())
+	}
+}
@@ -0,0 +1,309 @@
+package game
+
+import (
+	"math/bits"
+)
+
+// piece-square tables are laid out like the board, a8 first, and
+// are from white's point of view. black looks them up mirrored
+var (
+	mgPieceValues = [King + 1]int{Pawn: 82, Knight: 337, Bishop: 365, Rook: 477, Queen: 1025}
+	egPieceValues = [King + 1]int{Pawn: 94, Knight: 281, Bishop: 297, Rook: 512, Queen: 936}
+
+	// how much each piece counts towards the middlegame,
+	// a full set of pieces adds up to totalPhase
+	phaseWeights = [King + 1]int{Knight: 1, Bishop: 1, Rook: 2, Queen: 4}
+	totalPhase   = 24
+
+	mgTables = [King + 1][64]int{
+		Pawn: {
+			0, 0, 0, 0, 0, 0, 0, 0,
+			50, 50, 50, 50, 50, 50, 50, 50,
+			10, 10, 20, 30, 30, 20, 10, 10,
+			5, 5, 10, 25, 25, 10, 5, 5,
+			0, 0, 0, 20, 20, 0, 0, 0,
+			5, -5, -10, 0, 0, -10, -5, 5,
+			5, 10, 10, -20, -20, 10, 10, 5,
+			0, 0, 0, 0, 0, 0, 0, 0,
+		},
+		Knight: {
+			-50, -40, -30, -30, -30, -30, -40, -50,
+			-40, -20, 0, 0, 0, 0, -20, -40,
+			-30, 0, 10, 15, 15, 10, 0, -30,
+			-30, 5, 15, 20, 20, 15, 5, -30,
+			-30, 0, 15, 20, 20, 15, 0, -30,
+			-30, 5, 10, 15, 15, 10, 5, -30,
+			-40, -20, 0, 5, 5, 0, -20, -40,
+			-50, -40, -30, -30, -30, -30, -40, -50,
+		},
+		Bishop: {
+			-20, -10, -10, -10, -10, -10, -10, -20,
+			-10, 0, 0, 0, 0, 0, 0, -10,
+			-10, 0, 5, 10, 10, 5, 0, -10,
+			-10, 5, 5, 10, 10, 5, 5, -10,
+			-10, 0, 10, 10, 10, 10, 0, -10,
+			-10, 10, 10, 10, 10, 10, 10, -10,
+			-10, 5, 0, 0, 0, 0, 5, -10,
+			-20, -10, -10, -10, -10, -10, -10, -20,
+		},
+		Rook: {
+			0, 0, 0, 0, 0, 0, 0, 0,
+			5, 10, 10, 10, 10, 10, 10, 5,
+			-5, 0, 0, 0, 0, 0, 0, -5,
+			-5, 0, 0, 0, 0, 0, 0, -5,
+			-5, 0, 0, 0, 0, 0, 0, -5,
+			-5, 0, 0, 0, 0, 0, 0, -5,
+			-5, 0, 0, 0, 0, 0, 0, -5,
+			0, 0, 0, 5, 5, 0, 0, 0,
+		},
+		Queen: {
+			-20, -10, -10, -5, -5, -10, -10, -20,
+			-10, 0, 0, 0, 0, 0, 0, -10,
+			-10, 0, 5, 5, 5, 5, 0, -10,
+			-5, 0, 5, 5, 5, 5, 0, -5,
+			0, 0, 5, 5, 5, 5, 0, -5,
+			-10, 5, 5, 5, 5, 5, 0, -10,
+			-10, 0, 5, 0, 0, 0, 0, -10,
+			-20, -10, -10, -5, -5, -10, -10, -20,
+		},
+		King: {
+			-30, -40, -40, -50, -50, -40, -40, -30,
+			-30, -40, -40, -50, -50, -40, -40, -30,
+			-30, -40, -40, -50, -50, -40, -40, -30,
+			-30, -40, -40, -50, -50, -40, -40, -30,
+			-20, -30, -30, -40, -40, -30, -30, -20,
+			-10, -20, -20, -20, -20, -20, -20, -10,
+			20, 20, 0, 0, 0, 0, 20, 20,
+			20, 30, 10, 0, 0, 10, 30, 20,
+		},
+	}
+
+	egTables = [King + 1][64]int{
+		Pawn: {
+			0, 0, 0, 0, 0, 0, 0, 0,
+			80, 80, 80, 80, 80, 80, 80, 80,
+			50, 50, 50, 50, 50, 50, 50, 50,
+			30, 30, 30, 30, 30, 30, 30, 30,
+			20, 20, 20, 20, 20, 20, 20, 20,
+			10, 10, 10, 10, 10, 10, 10, 10,
+			0, 0, 0, 0, 0, 0, 0, 0,
+			0, 0, 0, 0, 0, 0, 0, 0,
+		},
+		King: {
+			-50, -40, -30, -20, -20, -30, -40, -50,
+			-30, -20, -10, 0, 0, -10, -20, -30,
+			-30, -10, 20, 30, 30, 20, -10, -30,
+			-30, -10, 30, 40, 40, 30, -10, -30,
+			-30, -10, 30, 40, 40, 30, -10, -30,
+			-30, -10, 20, 30, 30, 20, -10, -30,
+			-30, -30, 0, 0, 0, 0, -30, -30,
+			-50, -30, -30, -30, -30, -30, -30, -50,
+		},
+	}
+
+	// bonus for a passed pawn by its rank
+	mgPassedPawn = [8]int{0, 5, 10, 15, 25, 40, 60, 0}
+	egPassedPawn = [8]int{0, 10, 20, 35, 60, 90, 130, 0}
+
+	// mobility per square a piece can move to
+	mgMobility = [King + 1]int{Knight: 4, Bishop: 5, Rook: 2, Queen: 1}
+	egMobility = [King + 1]int{Knight: 4, Bishop: 5, Rook: 4, Queen: 2}
+
+	// how dangerous each piece is when it attacks the king's surroundings
+	kingAttackWeights = [King + 1]int{Knight: 2, Bishop: 2, Rook: 3, Queen: 5}
+)
+
+const (
+	mgDoubledPawn  = -10
+	egDoubledPawn  = -20
+	mgIsolatedPawn = -10
+	egIsolatedPawn = -15
+	mgBishopPair   = 30
+	egBishopPair   = 50
+	pawnShield     = 10
+	kingZoneAttack = -4
+)
+
+var (
+	fileMasks         [8]uint64
+	adjacentFileMasks [8]uint64
+	// the squares in front of a pawn on its own and the
+	// neighbouring files, which no enemy pawn may be on
+	// for it to be passed
+	passedPawnMasks [2][64]uint64
+)
+
+func init() {
+	for sq := 0; sq < 64; sq++ {
+		fileMasks[getFileForIdx(sq)] |= bit(sq)
+	}
+	for file := 0; file < 8; file++ {
+		if file > 0 {
+			adjacentFileMasks[file] |= fileMasks[file-1]
+		}
+		if file < 7 {
+			adjacentFileMasks[file] |= fileMasks[file+1]
+		}
+	}
+	for sq := 0; sq < 64; sq++ {
+		files := fileMasks[getFileForIdx(sq)] | adjacentFileMasks[getFileForIdx(sq)]
+		for other := 0; other < 64; other++ {
+			if files&bit(other) == 0 {
+				continue
+			}
+			if getRankForIdx(other) < getRankForIdx(sq) {
+				passedPawnMasks[colorIndex(White)][sq] |= bit(other)
+			}
+			if getRankForIdx(other) > getRankForIdx(sq) {
+				passedPawnMasks[colorIndex(Black)][sq] |= bit(other)
+			}
+		}
+	}
+}
+
+// Evaluate scores the position in centipawns from white's point of
+// view. the middlegame and endgame scores are blended by how much
+// material is left so nothing jumps when pieces come off
+func Evaluate(g *Game) int {
+	var mg, eg [2]int
+	phase := 0
+	bb := &g.bitboards
+	for c := 0; c < 2; c++ {
+		them := 1 - c
+		own := bb.colors[c]
+		king := bits.TrailingZeros64(bb.pieces[c][King])
+		enemyKingZone := kingAttacks[bits.TrailingZeros64(bb.pieces[them][King])]
+		var enemyPawnAttacks uint64
+		enemyPawns := bb.pieces[them][Pawn]
+		for enemyPawns != 0 {
+			enemyPawnAttacks |= pawnAttacks[them][popLSB(&enemyPawns)]
+		}
+
+		kingDanger := 0
+		for piece := Pawn; piece <= King; piece++ {
+			pieces := bb.pieces[c][piece]
+			for pieces != 0 {
+				sq := popLSB(&pieces)
+				tableSq := sq
+				if c == colorIndex(Black) {
+					tableSq = sq ^ 56
+				}
+				mg[c] += mgPieceValues[piece] + mgTables[piece][tableSq]
+				egTable := egTables[piece]
+				if piece != Pawn && piece != King {
+					egTable = mgTables[piece]
+				}
+				eg[c] += egPieceValues[piece] + egTable[tableSq]
+				phase += phaseWeights[piece]
+
+				var attacks uint64
+				switch piece {
+				case Knight:
+					attacks = knightAttacks[sq]
+					break
+				case Bishop:
+					attacks = bishopAttacks(sq, bb.occupied)
+					break
+				case Rook:
+					attacks = rookAttacks(sq, bb.occupied)
+					break
+				case Queen:
+					attacks = bishopAttacks(sq, bb.occupied) | rookAttacks(sq, bb.occupied)
+					break
+				default:
+					continue
+				}
+				mobility := bits.OnesCount64(attacks &^ own &^ enemyPawnAttacks)
+				mg[c] += mobility * mgMobility[piece]
+				eg[c] += mobility * egMobility[piece]
+				kingDanger += bits.OnesCount64(attacks&enemyKingZone) * kingAttackWeights[piece]
+			}
+		}
+		// king attacks only count in the middlegame
+		mg[them] += kingDanger * kingZoneAttack
+
+		if bits.OnesCount64(bb.pieces[c][Bishop]) >= 2 {
+			mg[c] += mgBishopPair
+			eg[c] += egBishopPair
+		}
+
+		pawnMg, pawnEg := g.pawnStructure(c)
+		mg[c] += pawnMg
+		eg[c] += pawnEg
+		mg[c] += g.pawnShield(c, king)
+	}
+
+	if phase > totalPhase {
+		phase = totalPhase
+	}
+	white := colorIndex(White)
+	black := colorIndex(Black)
+	mgScore := mg[white] - mg[black]
+	egScore := eg[white] - eg[black]
+	return (mgScore*phase + egScore*(totalPhase-phase)) / totalPhase
+}
+
+// pawnStructure scores doubled, isolated and passed pawns of color index c
+func (g *Game) pawnStructure(c int) (int, int) {
+	bb := &g.bitboards
+	pawns := bb.pieces[c][Pawn]
+	enemyPawns := bb.pieces[1-c][Pawn]
+	mg, eg := 0, 0
+	for file := 0; file < 8; file++ {
+		n := bits.OnesCount64(pawns & fileMasks[file])
+		if n == 0 {
+			continue
+		}
+		if n > 1 {
+			mg += (n - 1) * mgDoubledPawn
+			eg += (n - 1) * egDoubledPawn
+		}
+		if pawns&adjacentFileMasks[file] == 0 {
+			mg += n * mgIsolatedPawn
+			eg += n * egIsolatedPawn
+		}
+	}
+	for remaining := pawns; remaining != 0; {
+		sq := popLSB(&remaining)
+		if passedPawnMasks[c][sq]&enemyPawns != 0 {
+			continue
+		}
+		// ranks counted from the pawn's own side, 1 is where it starts
+		rank := 7 - getRankForIdx(sq)
+		if c == colorIndex(Black) {
+			rank = getRankForIdx(sq)
+		}
+		mg += mgPassedPawn[rank]
+		eg += egPassedPawn[rank]
+	}
+	return mg, eg
+}
+
+// pawnShield rewards pawns standing in front of a king
+// that is still on its back two ranks
+func (g *Game) pawnShield(c, king int) int {
+	rank := getRankForIdx(king)
+	forward := -1
+	if c == colorIndex(White) {
+		if rank < 6 {
+			return 0
+		}
+	} else {
+		if rank > 1 {
+			return 0
+		}
+		forward = 1
+	}
+	score := 0
+	file := getFileForIdx(king)
+	for df := -1; df <= 1; df++ {
+		for dr := 1; dr <= 2; dr++ {
+			sq, ok := idxForRankAndFile(rank+dr*forward, file+df)
+			if ok && g.bitboards.pieces[c][Pawn]&bit(sq) != 0 {
+				score += pawnShield
+				break
+			}
+		}
+	}
+	return score
+}
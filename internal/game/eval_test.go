@@ -0,0 +1,94 @@
+package game
+
+import (
+	"strings"
+	"testing"
+	"unicode"
+)
+
+// mirrorFEN flips the board top to bottom and swaps the colors
+func mirrorFEN(fen string) string {
+	fields := strings.Fields(fen)
+	ranks := strings.Split(fields[0], "/")
+	for i, j := 0, len(ranks)-1; i < j; i, j = i+1, j-1 {
+		ranks[i], ranks[j] = ranks[j], ranks[i]
+	}
+	swap := func(r rune) rune {
+		if unicode.IsUpper(r) {
+			return unicode.ToLower(r)
+		}
+		return unicode.ToUpper(r)
+	}
+	fields[0] = strings.Map(swap, strings.Join(ranks, "/"))
+	if fields[1] == "w" {
+		fields[1] = "b"
+	} else {
+		fields[1] = "w"
+	}
+	if fields[2] != "-" {
+		fields[2] = strings.Map(swap, fields[2])
+	}
+	if fields[3] != "-" {
+		rank := '6'
+		if fields[3][1] == '6' {
+			rank = '3'
+		}
+		fields[3] = fields[3][:1] + string(rank)
+	}
+	return strings.Join(fields, " ")
+}
+
+func TestEvaluateSymmetric(t *testing.T) {
+	g := New(STARTING_POSITION)
+	if score := Evaluate(&g); score != 0 {
+		t.Errorf("expected the starting position to be level, got %d", score)
+	}
+	for _, tt := range perftTests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New(tt.fen)
+			mirrored := New(mirrorFEN(tt.fen))
+			if a, b := Evaluate(&g), Evaluate(&mirrored); a != -b {
+				t.Errorf("expected mirrored scores to be opposite, got %d and %d", a, b)
+			}
+		})
+	}
+}
+
+func TestEvaluateTerms(t *testing.T) {
+	tests := []struct {
+		name   string
+		better string
+		worse  string
+	}{
+		{
+			name:   "extra queen",
+			better: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			worse:  "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNB1KBNR w KQkq - 0 1",
+		},
+		{
+			// same material, only whether the a pawn stands in front of b5 differs
+			name:   "passed pawn",
+			better: "4k3/8/8/pP6/8/8/P7/4K3 w - - 0 1",
+			worse:  "4k3/8/p7/1P6/8/8/P7/4K3 w - - 0 1",
+		},
+		{
+			name:   "doubled pawns",
+			better: "4k3/pp6/8/8/8/8/PP6/4K3 w - - 0 1",
+			worse:  "4k3/pp6/8/8/8/1P6/1P6/4K3 w - - 0 1",
+		},
+		{
+			name:   "pawn shield",
+			better: "6k1/5ppp/8/3q4/3Q4/8/5PPP/6K1 w - - 0 1",
+			worse:  "6k1/5ppp/8/3q4/3Q4/8/5PPP/1K6 w - - 0 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			better := New(tt.better)
+			worse := New(tt.worse)
+			if a, b := Evaluate(&better), Evaluate(&worse); a <= b {
+				t.Errorf("expected %d to be more than %d", a, b)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+func Parse(fen string) (Game, error) {
+	split := strings.Fields(fen)
+	if len(split) < 4 || len(split) > 6 {
+		return Game{}, fmt.Errorf("expected 4 to 6 fields in fen, got %d", len(split))
+	}
+	board, err := parseBoard(split[0])
+	if err != nil {
+		return Game{}, err
+	}
+	err = validateBoard(board)
+	if err != nil {
+		return Game{}, err
+	}
+	if split[1] != "w" && split[1] != "b" {
+		return Game{}, fmt.Errorf("side to move must be w or b, got %s", split[1])
+	}
+	toMove := split[1][0]
+	castleRights, err := parseCastleRights(split[2], board)
+	if err != nil {
+		return Game{}, err
+	}
+	enPassant, err := parseEnPassant(split[3], board, toMove)
+	if err != nil {
+		return Game{}, err
+	}
+	halfmoveClock := 0
+	if len(split) > 4 {
+		halfmoveClock, err = strconv.Atoi(split[4])
+		if err != nil || halfmoveClock < 0 {
+			return Game{}, fmt.Errorf("invalid halfmove clock: %s", split[4])
+		}
+	}
+	fullmoveNumber := 1
+	if len(split) > 5 {
+		fullmoveNumber, err = strconv.Atoi(split[5])
+		if err != nil || fullmoveNumber < 1 {
+			return Game{}, fmt.Errorf("invalid fullmove number: %s", split[5])
+		}
+	}
+	g := Game{
+		board:          board,
+		trackedMoves:   make([]TrackedMove, 0),
+		toMove:         toMove,
+		castleRights:   castleRights,
+		enPassant:      enPassant,
+		halfmoveClock:  halfmoveClock,
+		fullmoveNumber: fullmoveNumber,
+		legalMoves:     nil,
+		attackingMoves: nil,
+		status:         Ongoing,
+	}
+	for idx, piece := range board {
+		if piece != None {
+			g.bitboards.put(idx, piece)
+		}
+	}
+	if g.kingAttacked(1 - colorIndex(g.colorToMove())) {
+		return Game{}, errors.New("side not to move is in check")
+	}
+	g.generateMoves(&g.moves)
+	g.hash = g.computeHash()
+	g.positions = []uint64{g.repetitionKey()}
+	g.updateStatus()
+	g.startingFEN = g.FEN()
+	return g, nil
+}
+
+func (g *Game) FEN() string {
+	var sb strings.Builder
+	sb.WriteString(g.board.fen())
+	sb.WriteByte(' ')
+	sb.WriteByte(g.toMove)
+	sb.WriteByte(' ')
+	sb.WriteString(g.castleRights.String())
+	sb.WriteByte(' ')
+	sb.WriteString(g.enPassantTarget())
+	sb.WriteByte(' ')
+	sb.WriteString(strconv.Itoa(g.halfmoveClock))
+	sb.WriteByte(' ')
+	sb.WriteString(strconv.Itoa(g.fullmoveNumber))
+	return sb.String()
+}
+
+func (g *Game) HalfmoveClock() int {
+	return g.halfmoveClock
+}
+
+func (g *Game) FullmoveNumber() int {
+	return g.fullmoveNumber
+}
+
+// enPassantTarget returns the square a pawn would capture
+// onto, which is the one behind the pawn that just pushed.
+// internally we keep the pushed pawn's own square instead
+func (g *Game) enPassantTarget() string {
+	if g.enPassant == -1 {
+		return "-"
+	}
+	if g.toMove == 'b' {
+		return idxToSquare(g.enPassant + 8)
+	}
+	return idxToSquare(g.enPassant - 8)
+}
+
+func validateBoard(board Board) error {
+	whiteKings := 0
+	blackKings := 0
+	for idx, piece := range board {
+		switch piece {
+		case King | White:
+			whiteKings++
+			break
+		case King | Black:
+			blackKings++
+			break
+		case Pawn | White, Pawn | Black:
+			rank := getRankForIdx(idx)
+			if rank == 0 || rank == 7 {
+				return fmt.Errorf("pawn on back rank at %s", idxToSquare(idx))
+			}
+			break
+		}
+	}
+	if whiteKings != 1 {
+		return fmt.Errorf("expected 1 white king, got %d", whiteKings)
+	}
+	if blackKings != 1 {
+		return fmt.Errorf("expected 1 black king, got %d", blackKings)
+	}
+	return nil
+}
+
+func parseCastleRights(castleRights string, board Board) (CastleRights, error) {
+	var res CastleRights
+	if castleRights == "-" {
+		return res, nil
+	}
+	for _, ch := range []byte(castleRights) {
+		var right *bool
+		var king, rook int
+		var color Piece
+		switch ch {
+		case 'K':
+			right, king, rook, color = &res.WhiteKing, 60, 63, White
+			break
+		case 'Q':
+			right, king, rook, color = &res.WhiteQueen, 60, 56, White
+			break
+		case 'k':
+			right, king, rook, color = &res.BlackKing, 4, 7, Black
+			break
+		case 'q':
+			right, king, rook, color = &res.BlackQueen, 4, 0, Black
+			break
+		default:
+			return res, fmt.Errorf("invalid castling rights: %s", castleRights)
+		}
+		if *right {
+			return res, fmt.Errorf("duplicate castling right %c", ch)
+		}
+		if board[king] != King|color || board[rook] != Rook|color {
+			return res, fmt.Errorf("castling right %c without king and rook on their starting squares", ch)
+		}
+		*right = true
+	}
+	return res, nil
+}
+
+func parseEnPassant(target string, board Board, toMove byte) (int, error) {
+	if target == "-" {
+		return -1, nil
+	}
+	idx, ok := squareToIdx(target)
+	if !ok {
+		return -1, fmt.Errorf("invalid en passant square: %s", target)
+	}
+	rank := getRankForIdx(idx)
+	var pawn, origin int
+	var pushed Piece
+	if toMove == 'w' {
+		if rank != 2 {
+			return -1, fmt.Errorf("impossible en passant square %s with white to move", target)
+		}
+		pawn, origin, pushed = idx+8, idx-8, Pawn|Black
+	} else {
+		if rank != 5 {
+			return -1, fmt.Errorf("impossible en passant square %s with black to move", target)
+		}
+		pawn, origin, pushed = idx-8, idx+8, Pawn|White
+	}
+	if board[pawn] != pushed || board.hasPieceOnIdx(idx) || board.hasPieceOnIdx(origin) {
+		return -1, fmt.Errorf("impossible en passant square %s", target)
+	}
+	return pawn, nil
+}
+
+func (cr CastleRights) String() string {
+	var res string
+	if cr.WhiteKing {
+		res += "K"
+	}
+	if cr.WhiteQueen {
+		res += "Q"
+	}
+	if cr.BlackKing {
+		res += "k"
+	}
+	if cr.BlackQueen {
+		res += "q"
+	}
+	if res == "" {
+		return "-"
+	}
+	return res
+}
@@ -0,0 +1,76 @@
+package game
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		fen      string
+		expected string
+	}{
+		{"six fields", STARTING_POSITION, STARTING_POSITION},
+		{"no fullmove number", "4k3/8/8/8/8/8/4P3/4K3 b - - 7", "4k3/8/8/8/8/8/4P3/4K3 b - - 7 1"},
+		{"no clocks", "4k3/8/8/8/8/8/4P3/4K3 w - -", "4k3/8/8/8/8/8/4P3/4K3 w - - 0 1"},
+		{"en passant", "rnbqkbnr/ppp1pppp/8/8/3pP3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 3", "rnbqkbnr/ppp1pppp/8/8/3pP3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 3"},
+		{"some castling rights", "r3k2r/8/8/8/8/8/8/R3K2R w Kq - 0 1", "r3k2r/8/8/8/8/8/8/R3K2R w Kq - 0 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := Parse(tt.fen)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if fen := g.FEN(); fen != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, fen)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+		err  string
+	}{
+		{"too few fields", "4k3/8/8/8/8/8/8/4K3 w -", "expected 4 to 6 fields in fen, got 3"},
+		{"too many fields", "4k3/8/8/8/8/8/8/4K3 w - - 0 1 1", "expected 4 to 6 fields in fen, got 7"},
+		{"too few ranks", "4k3/8/8/8/8/8/4K3 w - - 0 1", "expected 8 ranks in piece placement, got 7"},
+		{"too many ranks", "4k3/8/8/8/8/8/8/8/4K3 w - - 0 1", "expected 8 ranks in piece placement, got 9"},
+		{"short rank", "4k3/8/8/8/7/8/8/4K3 w - - 0 1", "rank 4 has 7 squares, expected 8: 7"},
+		{"long rank", "4k3/8/8/8/8/8/8/4K3P w - - 0 1", "rank 1 has more than 8 squares: 4K3P"},
+		{"long rank of empty squares", "4k3/8/8/8/8/8/8/4K4 w - - 0 1", "rank 1 has more than 8 squares: 4K4"},
+		{"empty square count of 0", "4k3/8/8/8/8/8/08/4K3 w - - 0 1", "invalid empty square count in rank 2: 08"},
+		{"adjacent empty square counts", "4k3/8/8/8/8/8/44/4K3 w - - 0 1", "invalid empty square count in rank 2: 44"},
+		{"unknown piece", "4k3/8/8/8/8/8/3X4/4K3 w - - 0 1", "unknown piece 'X' in rank 2"},
+		{"side to move", "4k3/8/8/8/8/8/8/4K3 x - - 0 1", "side to move must be w or b, got x"},
+		{"invalid castling rights", "r3k2r/8/8/8/8/8/8/R3K2R w KX - 0 1", "invalid castling rights: KX"},
+		{"duplicate castling right", "r3k2r/8/8/8/8/8/8/R3K2R w KK - 0 1", "duplicate castling right K"},
+		{"castling without the rook", "r3k3/8/8/8/8/8/8/R3K2R w k - 0 1", "castling right k without king and rook on their starting squares"},
+		{"castling without the king", "r3k2r/8/8/8/8/8/8/R4K1R w Q - 0 1", "castling right Q without king and rook on their starting squares"},
+		{"invalid en passant square", "4k3/8/8/8/8/8/8/4K3 w - z9 0 1", "invalid en passant square: z9"},
+		{"en passant on the wrong rank", "4k3/8/8/8/4P3/8/8/4K3 b - e4 0 1", "impossible en passant square e4 with black to move"},
+		{"en passant with white to move on rank 3", "4k3/8/8/8/4P3/8/8/4K3 w - e3 0 1", "impossible en passant square e3 with white to move"},
+		{"en passant without a pushed pawn", "4k3/8/8/8/8/8/8/4K3 b - e3 0 1", "impossible en passant square e3"},
+		{"white pawn on the back rank", "4k2P/8/8/8/8/8/8/4K3 w - - 0 1", "pawn on back rank at h8"},
+		{"black pawn on the back rank", "4k3/8/8/8/8/8/8/p3K3 w - - 0 1", "pawn on back rank at a1"},
+		{"no white king", "4k3/8/8/8/8/8/8/8 w - - 0 1", "expected 1 white king, got 0"},
+		{"two black kings", "4k2k/8/8/8/8/8/8/4K3 w - - 0 1", "expected 1 black king, got 2"},
+		{"side not to move in check", "4k3/8/8/8/8/8/8/4K2r b - - 0 1", "side not to move is in check"},
+		{"negative halfmove clock", "4k3/8/8/8/8/8/8/4K3 w - - -1 1", "invalid halfmove clock: -1"},
+		{"halfmove clock not a number", "4k3/8/8/8/8/8/8/4K3 w - - x 1", "invalid halfmove clock: x"},
+		{"zero fullmove number", "4k3/8/8/8/8/8/8/4K3 w - - 0 0", "invalid fullmove number: 0"},
+		{"negative fullmove number", "4k3/8/8/8/8/8/8/4K3 w - - 0 -3", "invalid fullmove number: -3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := Parse(tt.fen)
+			if err == nil {
+				t.Fatalf("expected an error, got %s", g.FEN())
+			}
+			if err.Error() != tt.err {
+				t.Errorf("expected %q, got %q", tt.err, err)
+			}
+		})
+	}
+}
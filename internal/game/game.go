@@ -1,7 +1,8 @@
 package game
 
 import (
-	"strings"
+	"errors"
+	"fmt"
 
 	"github.com/vincer2040/chess/internal/types"
 )
@@ -15,167 +16,423 @@ const (
 	BlackCastleQueen
 )
 
+const STARTING_POSITION = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
 type Game struct {
+	startingFEN    string
 	board          Board
+	bitboards      bitboards
 	trackedMoves   []TrackedMove
 	toMove         byte
 	castleRights   CastleRights
 	enPassant      int
+	halfmoveClock  int
+	fullmoveNumber int
+	moves          MoveList
 	legalMoves     LegalMoves
 	attackingMoves AttackingMoves
+	status         Status
+	hash           uint64
+	// key of every position reached, for finding repetitions
+	positions []uint64
 }
 
 func New(fen string) Game {
-	split := strings.Split(fen, " ")
-	p := split[0]
-	board := newBoard(p)
-	toMove := byte(split[1][0])
-	castleRights := split[2]
-	g := Game{
-		board:          board,
-		trackedMoves:   make([]TrackedMove, 0),
-		toMove:         toMove,
-		castleRights:   newCastleRights(castleRights),
-		enPassant:      -1,
-		legalMoves:     nil,
-		attackingMoves: nil,
-	}
-	g.attackingMoves = getAttackingMoves(g.board, g.toMove)
-	g.legalMoves = getLegalMoves(g.board, g.toMove, &g.castleRights, g.enPassant, g.attackingMoves)
+	g, err := Parse(fen)
+	if err != nil {
+		panic(err)
+	}
 	return g
 }
 
-func (g *Game) MakeMove(move *types.Move) {
+func (g *Game) MakeMove(move *types.Move) error {
+	err := g.validateMove(move)
+	if err != nil {
+		return err
+	}
+	if g.isPromotion(move) {
+		return fmt.Errorf("move %s%s must be a promotion", idxToSquare(move.From), idxToSquare(move.To))
+	}
+	g.makeMove(move)
+	return nil
+}
+
+// makeMove plays a move that is known to be legal
+func (g *Game) makeMove(move *types.Move) {
 	movedPiece := g.board[move.From]
 	captured := g.board[move.To]
 	trackedMove := newTrackedMove(movedPiece, captured, move.From, move.To, false, None)
-	g.board[move.To] = movedPiece
-	g.board[move.From] = None
+	g.saveState(&trackedMove)
+	if captured != None {
+		g.removePiece(move.To)
+	}
+	g.removePiece(move.From)
+	g.putPiece(move.To, movedPiece)
 
 	if trackedMove.isCastle() {
 		g.castle(move)
 	}
 
-	disablesCastle, disabledCastleDirections := trackedMove.disablesCastle(&g.castleRights)
-	if disablesCastle {
-		g.disableCastle(disabledCastleDirections)
-	}
+	var disabled [4]DisabledCastleDirection
+	g.disableCastle(trackedMove.disablesCastle(disabled[:0]))
 
 	if trackedMove.isEnPassant() {
-		g.board[g.enPassant] = None
+		g.removePiece(g.enPassant)
 	}
 
 	if trackedMove.isDoublePawnPush() {
-		g.enPassant = move.To
+		g.setEnPassant(move.To)
+	} else {
+		g.setEnPassant(-1)
+	}
+
+	if movedPiece&PIECEMASK == Pawn || trackedMove.isCapture() {
+		g.halfmoveClock = 0
 	} else {
-		g.enPassant = -1
+		g.halfmoveClock++
 	}
 
 	if g.toMove == 'w' {
 		g.toMove = 'b'
 	} else {
 		g.toMove = 'w'
+		g.fullmoveNumber++
 	}
+	g.hash ^= zobristBlackToMove
 
 	g.trackedMoves = append(g.trackedMoves, trackedMove)
-	g.attackingMoves = getAttackingMoves(g.board, g.toMove)
-	g.legalMoves = getLegalMoves(g.board, g.toMove, &g.castleRights, g.enPassant, g.attackingMoves)
+	g.attackingMoves = nil
+	g.legalMoves = nil
+	g.generateMoves(&g.moves)
+	g.positions = append(g.positions, g.repetitionKey())
+	g.updateStatus()
 }
 
-func (g *Game) MakePromotion(promotion *types.Promotion) {
+func (g *Game) MakePromotion(promotion *types.Promotion) error {
+	err := g.validateMove(&promotion.Move)
+	if err != nil {
+		return err
+	}
+	if !g.isPromotion(&promotion.Move) {
+		return fmt.Errorf("move %s%s is not a promotion", idxToSquare(promotion.From), idxToSquare(promotion.To))
+	}
+	if _, ok := promotedToPiece(promotion.PromoteTo); !ok {
+		return fmt.Errorf("unknown promotion piece: %d", promotion.PromoteTo)
+	}
+	g.makePromotion(promotion)
+	return nil
+}
+
+// makePromotion plays a promotion that is known to be legal
+func (g *Game) makePromotion(promotion *types.Promotion) {
 	movedPiece := g.board[promotion.From]
 	captured := g.board[promotion.To]
-	var promotedTo Piece
-	switch promotion.PromoteTo {
-	case types.KnightPromotion:
-		promotedTo = Knight
-		break
-	case types.BishopPromotion:
-		promotedTo = Bishop
-		break
-	case types.RookPromotion:
-		promotedTo = Rook
-		break
-	case types.QueenPromotion:
-		promotedTo = Queen
-		break
-	}
+	promotedTo, _ := promotedToPiece(promotion.PromoteTo)
 	if g.toMove == 'w' {
 		promotedTo |= White
 	} else {
 		promotedTo |= Black
 	}
-    g.board[promotion.To] = promotedTo
-    g.board[promotion.From] = None
 	trackedMove := newTrackedMove(movedPiece, captured, promotion.From, promotion.To, true, promotedTo)
-
-	disablesCast, disabledcastleDirections := trackedMove.disablesCastle(&g.castleRights)
-	if disablesCast {
-		g.disableCastle(disabledcastleDirections)
+	g.saveState(&trackedMove)
+	if captured != None {
+		g.removePiece(promotion.To)
 	}
+	g.removePiece(promotion.From)
+	g.putPiece(promotion.To, promotedTo)
+
+	var disabled [4]DisabledCastleDirection
+	g.disableCastle(trackedMove.disablesCastle(disabled[:0]))
+	g.setEnPassant(-1)
+	g.halfmoveClock = 0
 	if g.toMove == 'w' {
 		g.toMove = 'b'
 	} else {
 		g.toMove = 'w'
+		g.fullmoveNumber++
 	}
+	g.hash ^= zobristBlackToMove
 
 	g.trackedMoves = append(g.trackedMoves, trackedMove)
-	g.attackingMoves = getAttackingMoves(g.board, g.toMove)
-	g.legalMoves = getLegalMoves(g.board, g.toMove, &g.castleRights, g.enPassant, g.attackingMoves)
+	g.attackingMoves = nil
+	g.legalMoves = nil
+	g.generateMoves(&g.moves)
+	g.positions = append(g.positions, g.repetitionKey())
+	g.updateStatus()
+}
+
+func (g *Game) UnmakeMove() error {
+	n := len(g.trackedMoves)
+	if n == 0 {
+		return errors.New("no moves to take back")
+	}
+	trackedMove := g.trackedMoves[n-1]
+	g.trackedMoves = g.trackedMoves[:n-1]
+	g.positions = g.positions[:len(g.positions)-1]
+
+	g.removePiece(trackedMove.To)
+	if trackedMove.Captured != None {
+		g.putPiece(trackedMove.To, trackedMove.Captured)
+	}
+	g.putPiece(trackedMove.From, trackedMove.Piece)
+
+	if g.toMove == 'w' {
+		g.toMove = 'b'
+		g.fullmoveNumber--
+	} else {
+		g.toMove = 'w'
+	}
+
+	if trackedMove.isEnPassant() {
+		if g.toMove == 'w' {
+			g.putPiece(trackedMove.prevEnPassant, Pawn|Black)
+		} else {
+			g.putPiece(trackedMove.prevEnPassant, Pawn|White)
+		}
+	}
+
+	if trackedMove.isCastle() {
+		g.uncastle(trackedMove.To)
+	}
+
+	g.castleRights = trackedMove.prevCastleRights
+	g.enPassant = trackedMove.prevEnPassant
+	g.halfmoveClock = trackedMove.prevHalfmoveClock
+	g.legalMoves = nil
+	g.attackingMoves = nil
+	g.status = trackedMove.prevStatus
+	g.hash = trackedMove.prevHash
+	g.generateMoves(&g.moves)
+	return nil
+}
+
+func (g *Game) saveState(trackedMove *TrackedMove) {
+	trackedMove.prevCastleRights = g.castleRights
+	trackedMove.prevEnPassant = g.enPassant
+	trackedMove.prevHalfmoveClock = g.halfmoveClock
+	trackedMove.prevStatus = g.status
+	trackedMove.prevHash = g.hash
+}
+
+func (g *Game) validateMove(move *types.Move) error {
+	if g.status.IsOver() {
+		return errors.New("game is over")
+	}
+	if move.From < 0 || move.From > 63 || move.To < 0 || move.To > 63 {
+		return fmt.Errorf("move out of bounds: %d to %d", move.From, move.To)
+	}
+	if !g.board.hasPieceOnIdx(move.From) {
+		return fmt.Errorf("no piece on %s", idxToSquare(move.From))
+	}
+	if !g.board.hasColorPieceOnIdx(move.From, g.colorToMove()) {
+		return fmt.Errorf("piece on %s does not belong to the side to move", idxToSquare(move.From))
+	}
+	for _, m := range g.moves.moves[:g.moves.n] {
+		if m.From() == move.From && m.To() == move.To {
+			return nil
+		}
+	}
+	return fmt.Errorf("illegal move %s%s", idxToSquare(move.From), idxToSquare(move.To))
+}
+
+func (g *Game) isPromotion(move *types.Move) bool {
+	if g.board[move.From]&PIECEMASK != Pawn {
+		return false
+	}
+	rank := getRankForIdx(move.To)
+	return rank == 0 || rank == 7
+}
+
+func (g *Game) colorToMove() Piece {
+	if g.toMove == 'w' {
+		return White
+	}
+	return Black
+}
+
+func (g *Game) StartingFEN() string {
+	return g.startingFEN
+}
+
+func (g *Game) TrackedMoves() []TrackedMove {
+	res := make([]TrackedMove, len(g.trackedMoves))
+	copy(res, g.trackedMoves)
+	return res
+}
+
+func (g *Game) ToMove() byte {
+	return g.toMove
 }
 
 func (g *Game) GetLegalMoves() LegalMoves {
+	if g.legalMoves == nil {
+		g.legalMoves = g.moves.LegalMoves()
+	}
 	return g.legalMoves
 }
 
+// Moves is the legal moves of the current position. it is
+// returned by value so callers can't change the game's copy
+func (g *Game) Moves() MoveList {
+	return g.moves
+}
+
+// Play makes a move taken from Moves. it only checks that the
+// move is legal, which is cheaper than MakeMove for search
+func (g *Game) Play(m Move) error {
+	if g.status.IsOver() {
+		return errors.New("game is over")
+	}
+	for _, legal := range g.moves.moves[:g.moves.n] {
+		if legal == m {
+			g.play(m)
+			return nil
+		}
+	}
+	return fmt.Errorf("illegal move %s", m)
+}
+
+// play makes a move known to be legal without
+// checking whether the game is already over
+func (g *Game) play(m Move) {
+	if m.IsPromotion() {
+		promotion := types.Promotion{
+			Move:      types.Move{From: m.From(), To: m.To()},
+			PromoteTo: pieceToPromotedTo(m.PromoteTo()),
+		}
+		g.makePromotion(&promotion)
+		return
+	}
+	g.makeMove(&types.Move{From: m.From(), To: m.To()})
+}
+
+// GetAttackingMoves is only worked out when asked for
+// since move generation doesn't need it
 func (g *Game) GetAttackingMoves() AttackingMoves {
+	if g.attackingMoves == nil {
+		g.attackingMoves = getAttackingMoves(g.board, g.toMove)
+	}
 	return g.attackingMoves
 }
 
+// Clone copies the game, including its history, so
+// the copy can be played on without touching the original
+func (g *Game) Clone() Game {
+	c := *g
+	c.trackedMoves = make([]TrackedMove, len(g.trackedMoves))
+	copy(c.trackedMoves, g.trackedMoves)
+	c.positions = make([]uint64, len(g.positions))
+	copy(c.positions, g.positions)
+	c.legalMoves = nil
+	c.attackingMoves = nil
+	return c
+}
+
+func (g *Game) PieceOn(idx int) Piece {
+	return g.board[idx]
+}
+
+func (g *Game) Board() Board {
+	return g.board
+}
+
 func (g *Game) PrintBoard() {
 	g.board.print()
 }
 
 func (g *Game) disableCastle(directions []DisabledCastleDirection) {
 	for _, dir := range directions {
+		var right *bool
 		switch dir {
 		case WhiteCastleKing:
-			g.castleRights.WhiteKing = false
+			right = &g.castleRights.WhiteKing
 			break
 		case WhiteCastleQueen:
-			g.castleRights.WhiteQueen = false
+			right = &g.castleRights.WhiteQueen
 			break
 		case BlackCastleKing:
-			g.castleRights.BlackKing = false
+			right = &g.castleRights.BlackKing
 			break
 		case BlackCastleQueen:
-			g.castleRights.BlackQueen = false
+			right = &g.castleRights.BlackQueen
 			break
+		default:
+			continue
+		}
+		if *right {
+			*right = false
+			g.hash ^= zobristCastle[dir]
 		}
 	}
 }
 
+var (
+	whiteCastles = [...]DisabledCastleDirection{WhiteCastleKing, WhiteCastleQueen}
+	blackCastles = [...]DisabledCastleDirection{BlackCastleKing, BlackCastleQueen}
+)
+
 func (g *Game) castle(move *types.Move) {
 	if g.toMove == 'w' {
 		if move.To == 62 {
-			g.board[63] = None
-			g.board[61] = Rook | White
+			g.moveRook(63, 61, Rook|White)
 		} else {
-			g.board[56] = None
-			g.board[59] = Rook | White
+			g.moveRook(56, 59, Rook|White)
 		}
-		g.castleRights.WhiteKing = false
-		g.castleRights.WhiteQueen = false
+		g.disableCastle(whiteCastles[:])
 	} else {
 		if move.To == 6 {
-			g.board[7] = None
-			g.board[5] = Rook | Black
+			g.moveRook(7, 5, Rook|Black)
 		} else {
-			g.board[0] = None
-			g.board[3] = Rook | Black
+			g.moveRook(0, 3, Rook|Black)
 		}
-		g.castleRights.BlackKing = false
-		g.castleRights.BlackQueen = false
+		g.disableCastle(blackCastles[:])
+	}
+}
+
+func (g *Game) moveRook(from, to int, rook Piece) {
+	g.removePiece(from)
+	g.putPiece(to, rook)
+}
+
+// putPiece and removePiece keep the board,
+// bitboards and hash in step with each other
+func (g *Game) putPiece(idx int, piece Piece) {
+	g.board[idx] = piece
+	g.bitboards.put(idx, piece)
+	g.hash ^= zobristPieces[piece][idx]
+}
+
+func (g *Game) removePiece(idx int) {
+	piece := g.board[idx]
+	g.board[idx] = None
+	g.bitboards.remove(idx, piece)
+	g.hash ^= zobristPieces[piece][idx]
+}
+
+func (g *Game) setEnPassant(enPassant int) {
+	if g.enPassant != -1 {
+		g.hash ^= zobristEnPassant[getFileForIdx(g.enPassant)]
+	}
+	if enPassant != -1 {
+		g.hash ^= zobristEnPassant[getFileForIdx(enPassant)]
+	}
+	g.enPassant = enPassant
+}
+
+func (g *Game) uncastle(kingTo int) {
+	switch kingTo {
+	case 62:
+		g.moveRook(61, 63, Rook|White)
+		break
+	case 58:
+		g.moveRook(59, 56, Rook|White)
+		break
+	case 6:
+		g.moveRook(5, 7, Rook|Black)
+		break
+	case 2:
+		g.moveRook(3, 0, Rook|Black)
+		break
 	}
 }
 
@@ -186,15 +443,3 @@ type CastleRights struct {
 	BlackQueen bool
 }
 
-func newCastleRights(castleRights string) CastleRights {
-	whiteKing := strings.Contains(castleRights, "K")
-	whiteQueen := strings.Contains(castleRights, "Q")
-	blackKing := strings.Contains(castleRights, "k")
-	blackQueen := strings.Contains(castleRights, "q")
-	return CastleRights{
-		WhiteKing:  whiteKing,
-		WhiteQueen: whiteQueen,
-		BlackKing:  blackKing,
-		BlackQueen: blackQueen,
-	}
-}
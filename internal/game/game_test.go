@@ -0,0 +1,178 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/vincer2040/chess/internal/types"
+)
+
+const foolsMate = "rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3"
+
+func TestMakeMoveErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+		move types.Move
+		err  string
+	}{
+		{"to a promotion square", "4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", types.Move{From: 9, To: 1}, "move b7b8 must be a promotion"},
+		{"wrong side", STARTING_POSITION, types.Move{From: 12, To: 28}, "piece on e7 does not belong to the side to move"},
+		{"illegal", STARTING_POSITION, types.Move{From: 52, To: 28}, "illegal move e2e5"},
+		{"pinned piece", "4r2k/8/8/8/8/8/4N3/4K3 w - - 0 1", types.Move{From: 52, To: 42}, "illegal move e2c3"},
+		{"empty square", STARTING_POSITION, types.Move{From: 36, To: 28}, "no piece on e4"},
+		{"out of bounds", STARTING_POSITION, types.Move{From: 64, To: 0}, "move out of bounds: 64 to 0"},
+		{"game over", foolsMate, types.Move{From: 52, To: 36}, "game is over"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New(tt.fen)
+			err := g.MakeMove(&tt.move)
+			if err == nil {
+				t.Fatalf("expected an error")
+			}
+			if err.Error() != tt.err {
+				t.Errorf("expected %q, got %q", tt.err, err)
+			}
+			if fen := g.FEN(); fen != tt.fen {
+				t.Errorf("expected the game to be left as %q, got %q", tt.fen, fen)
+			}
+		})
+	}
+}
+
+func TestMakePromotionErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		fen       string
+		promotion types.Promotion
+		err       string
+	}{
+		{"not a promotion", STARTING_POSITION, types.Promotion{Move: types.Move{From: 52, To: 36}, PromoteTo: types.QueenPromotion}, "move e2e4 is not a promotion"},
+		{"unknown piece", "4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", types.Promotion{Move: types.Move{From: 9, To: 1}, PromoteTo: 7}, "unknown promotion piece: 7"},
+		{"illegal", "4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", types.Promotion{Move: types.Move{From: 9, To: 0}, PromoteTo: types.QueenPromotion}, "illegal move b7a8"},
+		{"wrong side", "4k3/1P6/8/8/8/8/6p1/4K3 w - - 0 1", types.Promotion{Move: types.Move{From: 54, To: 62}, PromoteTo: types.QueenPromotion}, "piece on g2 does not belong to the side to move"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New(tt.fen)
+			err := g.MakePromotion(&tt.promotion)
+			if err == nil {
+				t.Fatalf("expected an error")
+			}
+			if err.Error() != tt.err {
+				t.Errorf("expected %q, got %q", tt.err, err)
+			}
+			if fen := g.FEN(); fen != tt.fen {
+				t.Errorf("expected the game to be left as %q, got %q", tt.fen, fen)
+			}
+		})
+	}
+}
+
+func TestMakePromotion(t *testing.T) {
+	g := New("4k3/1P6/8/8/8/8/8/4K3 w - - 0 1")
+	err := g.MakePromotion(&types.Promotion{Move: types.Move{From: 9, To: 1}, PromoteTo: types.KnightPromotion})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fen := g.FEN(); fen != "1N2k3/8/8/8/8/8/8/4K3 b - - 0 1" {
+		t.Errorf("unexpected position after promoting: %s", fen)
+	}
+}
+
+// checkUnmake plays every move to depth and checks that taking each
+// back leaves the game exactly as it was
+func checkUnmake(t *testing.T, g *Game, depth int) {
+	if depth == 0 {
+		return
+	}
+	fen, hash, status := g.FEN(), g.Hash(), g.Status()
+	positions := append([]uint64(nil), g.positions...)
+	moves := g.Moves()
+	for i := 0; i < moves.Len(); i++ {
+		m := moves.At(i)
+		if err := g.Play(m); err != nil {
+			t.Fatalf("%s: %s: %v", fen, m, err)
+		}
+		checkUnmake(t, g, depth-1)
+		if err := g.UnmakeMove(); err != nil {
+			t.Fatalf("%s: taking back %s: %v", fen, m, err)
+		}
+		if got := g.FEN(); got != fen {
+			t.Fatalf("%s: taking back %s left %s", fen, m, got)
+		}
+		if g.Hash() != hash || g.computeHash() != hash {
+			t.Fatalf("%s: taking back %s left the wrong hash", fen, m)
+		}
+		if g.Status() != status {
+			t.Fatalf("%s: taking back %s left status %s", fen, m, g.Status())
+		}
+		if len(g.positions) != len(positions) || g.positions[len(positions)-1] != positions[len(positions)-1] {
+			t.Fatalf("%s: taking back %s left the wrong repetition history", fen, m)
+		}
+		if after := g.Moves(); after.Len() != moves.Len() {
+			t.Fatalf("%s: taking back %s left %d moves, expected %d", fen, m, after.Len(), moves.Len())
+		}
+	}
+}
+
+func TestUnmakeMove(t *testing.T) {
+	for _, tt := range perftTests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New(tt.fen)
+			checkUnmake(t, &g, 3)
+		})
+	}
+}
+
+func TestUnmakeSpecialMoves(t *testing.T) {
+	tests := []struct {
+		name  string
+		fen   string
+		moves []string
+	}{
+		{"castling king side", "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 3 10", []string{"e1g1"}},
+		{"castling queen side", "r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 3 10", []string{"e8c8"}},
+		{"en passant", "4k3/8/8/8/3p4/8/4P3/4K3 w - - 0 1", []string{"e2e4", "d4e3"}},
+		{"promotion", "4k3/1P6/8/8/8/8/8/4K3 w - - 5 40", []string{"b7b8q"}},
+		{"capturing promotion", "r3k3/1P6/8/8/8/8/8/4K3 w q - 0 1", []string{"b7a8n"}},
+		{"capture", STARTING_POSITION, []string{"e2e4", "d7d5", "e4d5"}},
+		{"rook capture losing castling", "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", []string{"a1a8"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New(tt.fen)
+			var fens []string
+			var hashes []uint64
+			for _, m := range tt.moves {
+				fens = append(fens, g.FEN())
+				hashes = append(hashes, g.Hash())
+				playMoves(t, &g, m)
+			}
+			for i := len(tt.moves) - 1; i >= 0; i-- {
+				if err := g.UnmakeMove(); err != nil {
+					t.Fatalf("taking back %s: %v", tt.moves[i], err)
+				}
+				if fen := g.FEN(); fen != fens[i] {
+					t.Errorf("taking back %s: expected %s, got %s", tt.moves[i], fens[i], fen)
+				}
+				if g.Hash() != hashes[i] {
+					t.Errorf("taking back %s: wrong hash", tt.moves[i])
+				}
+			}
+			if g.Repetitions() != 1 {
+				t.Errorf("expected the starting position to have been seen once, got %d", g.Repetitions())
+			}
+		})
+	}
+}
+
+func TestUnmakeMoveWithoutHistory(t *testing.T) {
+	g := New(STARTING_POSITION)
+	if err := g.UnmakeMove(); err == nil {
+		t.Errorf("expected an error taking back with no moves played")
+	}
+	if fen := g.FEN(); fen != STARTING_POSITION {
+		t.Errorf("expected the game to be left alone, got %s", fen)
+	}
+}
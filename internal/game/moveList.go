@@ -0,0 +1,128 @@
+package game
+
+import (
+	"github.com/vincer2040/chess/internal/types"
+)
+
+// Move packs a move into 16 bits: the from square in the
+// low 6 bits, the to square in the next 6 and the flags on top
+type Move uint16
+
+type MoveFlag uint16
+
+const (
+	QuietMove MoveFlag = iota
+	DoublePawnPush
+	KingCastle
+	QueenCastle
+	Capture
+	EnPassantCapture
+)
+
+const (
+	// a promotion has this bit set and the piece in the low two bits.
+	// with the capture bit added it is a capturing promotion
+	PromotionFlag MoveFlag = 8
+	captureBit    MoveFlag = 4
+)
+
+// the most legal moves any position can have is 218
+const maxMoves = 256
+
+func newMove(from, to int, flags MoveFlag) Move {
+	return Move(from) | Move(to)<<6 | Move(flags)<<12
+}
+
+func (m Move) From() int {
+	return int(m & 0x3f)
+}
+
+func (m Move) To() int {
+	return int(m>>6) & 0x3f
+}
+
+func (m Move) Flags() MoveFlag {
+	return MoveFlag(m >> 12)
+}
+
+func (m Move) IsCapture() bool {
+	return m.Flags()&captureBit != 0
+}
+
+func (m Move) IsPromotion() bool {
+	return m.Flags()&PromotionFlag != 0
+}
+
+func (m Move) IsCastle() bool {
+	flags := m.Flags()
+	return flags == KingCastle || flags == QueenCastle
+}
+
+func (m Move) IsEnPassant() bool {
+	return m.Flags() == EnPassantCapture
+}
+
+// PromoteTo is the type of piece a promotion turns into, without a color
+func (m Move) PromoteTo() Piece {
+	if !m.IsPromotion() {
+		return None
+	}
+	return Knight + Piece(m.Flags()&3)
+}
+
+func (m Move) Data() types.Data {
+	move := types.Move{From: m.From(), To: m.To()}
+	if !m.IsPromotion() {
+		return types.Data{Type: types.MoveType, Data: move}
+	}
+	return types.Data{
+		Type: types.PromotionType,
+		Data: types.Promotion{Move: move, PromoteTo: pieceToPromotedTo(m.PromoteTo())},
+	}
+}
+
+// String is the move in uci notation
+func (m Move) String() string {
+	s := idxToSquare(m.From()) + idxToSquare(m.To())
+	if m.IsPromotion() {
+		s += string(pieceLetter(m.PromoteTo()) + ('a' - 'A'))
+	}
+	return s
+}
+
+// MoveList holds the legal moves of a position
+// in a fixed array so generating them doesn't allocate
+type MoveList struct {
+	moves [maxMoves]Move
+	n     int
+}
+
+func (ml *MoveList) Len() int {
+	return ml.n
+}
+
+func (ml *MoveList) At(i int) Move {
+	return ml.moves[i]
+}
+
+func (ml *MoveList) Swap(i, j int) {
+	ml.moves[i], ml.moves[j] = ml.moves[j], ml.moves[i]
+}
+
+func (ml *MoveList) add(m Move) {
+	ml.moves[ml.n] = m
+	ml.n++
+}
+
+// LegalMoves groups the moves by the square they start on.
+// a promotion is listed once rather than once per piece
+func (ml *MoveList) LegalMoves() LegalMoves {
+	legalMoves := make(LegalMoves)
+	for _, m := range ml.moves[:ml.n] {
+		if m.IsPromotion() && m.PromoteTo() != Queen {
+			continue
+		}
+		legalMoves[m.From()] = append(legalMoves[m.From()], m.To())
+	}
+	return legalMoves
+}
@@ -0,0 +1,71 @@
+package game
+
+import (
+	"testing"
+)
+
+func TestMoveFlags(t *testing.T) {
+	g := New("r3k2r/1P6/8/3pP3/8/8/8/R3K2R w KQkq d6 0 1")
+	moves := g.Moves()
+	flags := make(map[string]MoveFlag)
+	for i := 0; i < moves.Len(); i++ {
+		m := moves.At(i)
+		flags[m.String()] = m.Flags()
+	}
+	tests := []struct {
+		move  string
+		flags MoveFlag
+	}{
+		{"e1g1", KingCastle},
+		{"e1c1", QueenCastle},
+		{"e5d6", EnPassantCapture},
+		{"e5e6", QuietMove},
+		{"a1a8", Capture},
+		{"b7b8q", PromotionFlag | 3},
+		{"b7b8n", PromotionFlag},
+		{"b7a8r", PromotionFlag | Capture | 2},
+	}
+	for _, tt := range tests {
+		got, ok := flags[tt.move]
+		if !ok {
+			t.Errorf("%s was not generated", tt.move)
+			continue
+		}
+		if got != tt.flags {
+			t.Errorf("%s: expected flags %d, got %d", tt.move, tt.flags, got)
+		}
+	}
+}
+
+func TestMoveListLegalMoves(t *testing.T) {
+	g := New("4k3/1P6/8/8/8/8/8/4K3 w - - 0 1")
+	moves := g.Moves()
+	legalMoves := moves.LegalMoves()
+	// four promotions but one destination square
+	if len(legalMoves[9]) != 1 || legalMoves[9][0] != 1 {
+		t.Errorf("expected b7 to only list b8, got %v", legalMoves[9])
+	}
+	total := 0
+	for _, tos := range legalMoves {
+		total += len(tos)
+	}
+	if total != moves.Len()-3 {
+		t.Errorf("expected %d moves in the map, got %d", moves.Len()-3, total)
+	}
+}
+
+func TestMakeUnmakeAllocs(t *testing.T) {
+	for _, tt := range perftTests {
+		g := New(tt.fen)
+		moves := g.Moves()
+		allocs := testing.AllocsPerRun(10, func() {
+			for i := 0; i < moves.Len(); i++ {
+				g.play(moves.At(i))
+				g.UnmakeMove()
+			}
+		})
+		if allocs != 0 {
+			t.Errorf("%s: expected make and unmake not to allocate, got %v allocations", tt.name, allocs)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package game
+
+import (
+	"math/bits"
+)
+
+// generateMoves fills ml with every legal move for the side to move.
+// pieces pinned to the king may only move along the pin and
+// when in check every move has to capture the checker or block it
+func (g *Game) generateMoves(ml *MoveList) {
+	ml.n = 0
+	us := colorIndex(g.colorToMove())
+	them := 1 - us
+	bb := &g.bitboards
+	own := bb.colors[us]
+	occupied := bb.occupied
+	king := bits.TrailingZeros64(bb.pieces[us][King])
+
+	enemy := bb.colors[them]
+	add := func(from int, targets uint64) {
+		for targets != 0 {
+			to := popLSB(&targets)
+			if enemy&bit(to) != 0 {
+				ml.add(newMove(from, to, Capture))
+			} else {
+				ml.add(newMove(from, to, QuietMove))
+			}
+		}
+	}
+
+	// the king can't hide from a slider by stepping along its ray
+	withoutKing := occupied &^ bit(king)
+	var kingTargets uint64
+	targets := kingAttacks[king] &^ own
+	for targets != 0 {
+		to := popLSB(&targets)
+		if bb.attackersTo(to, them, withoutKing) == 0 {
+			kingTargets |= bit(to)
+		}
+	}
+
+	checkers := bb.attackersTo(king, them, occupied)
+	if bits.OnesCount64(checkers) > 1 {
+		// in double check only the king can move
+		add(king, kingTargets)
+		return
+	}
+
+	checkMask := ^uint64(0)
+	if checkers != 0 {
+		checker := bits.TrailingZeros64(checkers)
+		checkMask = between[king][checker] | checkers
+	}
+	add(king, kingTargets)
+	if checkers == 0 {
+		castles := g.castleTargets(us, them)
+		for castles != 0 {
+			to := popLSB(&castles)
+			if to > king {
+				ml.add(newMove(king, to, KingCastle))
+			} else {
+				ml.add(newMove(king, to, QueenCastle))
+			}
+		}
+	}
+
+	var pinRays [64]uint64
+	pinned := g.pinned(king, us, them, &pinRays)
+
+	pieces := &bb.pieces[us]
+	knights := pieces[Knight] &^ pinned
+	for knights != 0 {
+		from := popLSB(&knights)
+		add(from, knightAttacks[from]&^own&checkMask)
+	}
+	bishops := pieces[Bishop] | pieces[Queen]
+	for bishops != 0 {
+		from := popLSB(&bishops)
+		targets := bishopAttacks(from, occupied) &^ own & checkMask
+		if pinned&bit(from) != 0 {
+			targets &= pinRays[from]
+		}
+		add(from, targets)
+	}
+	rooks := pieces[Rook] | pieces[Queen]
+	for rooks != 0 {
+		from := popLSB(&rooks)
+		targets := rookAttacks(from, occupied) &^ own & checkMask
+		if pinned&bit(from) != 0 {
+			targets &= pinRays[from]
+		}
+		add(from, targets)
+	}
+
+	forward := -8
+	startRank := 6
+	if us == colorIndex(Black) {
+		forward = 8
+		startRank = 1
+	}
+	pawns := pieces[Pawn]
+	for pawns != 0 {
+		from := popLSB(&pawns)
+		var targets uint64
+		one := from + forward
+		if occupied&bit(one) == 0 {
+			targets |= bit(one)
+			two := one + forward
+			if getRankForIdx(from) == startRank && occupied&bit(two) == 0 {
+				targets |= bit(two)
+			}
+		}
+		targets |= pawnAttacks[us][from] & enemy
+		targets &= checkMask
+		if pinned&bit(from) != 0 {
+			targets &= pinRays[from]
+		}
+		for targets != 0 {
+			to := popLSB(&targets)
+			var flags MoveFlag
+			if enemy&bit(to) != 0 {
+				flags = Capture
+			} else if to-from == 2*forward {
+				flags = DoublePawnPush
+			}
+			if rank := getRankForIdx(to); rank == 0 || rank == 7 {
+				for promoteTo := Knight; promoteTo <= Queen; promoteTo++ {
+					ml.add(newMove(from, to, flags|PromotionFlag|MoveFlag(promoteTo-Knight)))
+				}
+				continue
+			}
+			ml.add(newMove(from, to, flags))
+		}
+		if g.enPassant != -1 {
+			target := g.enPassant + forward
+			if pawnAttacks[us][from]&bit(target) != 0 && g.enPassantIsLegal(from, target, king, us, them) {
+				ml.add(newMove(from, target, EnPassantCapture))
+			}
+		}
+	}
+}
+
+// pinned finds the pieces of color index us that are absolutely pinned
+// to the king and fills in the squares each one may still move to
+func (g *Game) pinned(king, us, them int, pinRays *[64]uint64) uint64 {
+	bb := &g.bitboards
+	var pinned uint64
+	enemy := bb.colors[them]
+	snipers := (rookAttacks(king, enemy) & (bb.pieces[them][Rook] | bb.pieces[them][Queen])) |
+		(bishopAttacks(king, enemy) & (bb.pieces[them][Bishop] | bb.pieces[them][Queen]))
+	for snipers != 0 {
+		sniper := popLSB(&snipers)
+		blockers := between[king][sniper] & bb.occupied
+		if bits.OnesCount64(blockers) == 1 && blockers&bb.colors[us] != 0 {
+			pinned |= blockers
+			pinRays[bits.TrailingZeros64(blockers)] = between[king][sniper] | bit(sniper)
+		}
+	}
+	return pinned
+}
+
+// enPassantIsLegal plays out the capture on the occupancy since taking
+// en passant removes two pawns from the same rank at once, which
+// the usual pin detection doesn't see
+func (g *Game) enPassantIsLegal(from, to, king, us, them int) bool {
+	bb := &g.bitboards
+	occupied := bb.occupied&^bit(from)&^bit(g.enPassant) | bit(to)
+	return bb.attackersTo(king, them, occupied)&^bit(g.enPassant) == 0
+}
+
+// castleTargets finds the squares the king may castle to. the king
+// can't pass through or land on an attacked square but the rook can
+func (g *Game) castleTargets(us, them int) uint64 {
+	bb := &g.bitboards
+	occupied := bb.occupied
+	attacked := func(squares ...int) bool {
+		for _, sq := range squares {
+			if bb.attackersTo(sq, them, occupied) != 0 {
+				return true
+			}
+		}
+		return false
+	}
+	rooks := bb.pieces[us][Rook]
+	var res uint64
+	if us == colorIndex(White) {
+		if g.castleRights.WhiteKing && rooks&bit(63) != 0 &&
+			occupied&(bit(61)|bit(62)) == 0 && !attacked(61, 62) {
+			res |= bit(62)
+		}
+		if g.castleRights.WhiteQueen && rooks&bit(56) != 0 &&
+			occupied&(bit(57)|bit(58)|bit(59)) == 0 && !attacked(58, 59) {
+			res |= bit(58)
+		}
+	} else {
+		if g.castleRights.BlackKing && rooks&bit(7) != 0 &&
+			occupied&(bit(5)|bit(6)) == 0 && !attacked(5, 6) {
+			res |= bit(6)
+		}
+		if g.castleRights.BlackQueen && rooks&bit(0) != 0 &&
+			occupied&(bit(1)|bit(2)|bit(3)) == 0 && !attacked(2, 3) {
+			res |= bit(2)
+		}
+	}
+	return res
+}
+
+// kingAttacked reports whether the king of color index c is in check
+func (g *Game) kingAttacked(c int) bool {
+	bb := &g.bitboards
+	king := bits.TrailingZeros64(bb.pieces[c][King])
+	return bb.attackersTo(king, 1-c, bb.occupied) != 0
+}
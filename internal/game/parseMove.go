@@ -0,0 +1,207 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vincer2040/chess/internal/types"
+)
+
+// ParseMove resolves a move written in either standard algebraic
+// notation ("Nf3", "exd5", "O-O", "e8=Q") or uci notation
+// ("e2e4", "e7e8q") against the current legal moves. the result
+// is a types.Move or a types.Promotion
+func (g *Game) ParseMove(s string) (types.Data, error) {
+	illegal := types.Data{Type: types.IllegalType, Data: nil}
+	trimmed := strings.TrimSpace(s)
+	trimmed = strings.TrimSuffix(trimmed, "e.p.")
+	trimmed = strings.TrimSpace(trimmed)
+	trimmed = strings.TrimRight(trimmed, "+#!?")
+	if trimmed == "" {
+		return illegal, fmt.Errorf("empty move: %q", s)
+	}
+	if g.status.IsOver() {
+		return illegal, fmt.Errorf("game is over, cannot play %s", s)
+	}
+	if data, ok := g.parseUCIMove(trimmed); ok {
+		return data, nil
+	}
+	return g.parseSANMove(trimmed, s)
+}
+
+func (g *Game) parseUCIMove(s string) (types.Data, bool) {
+	if len(s) != 4 && len(s) != 5 {
+		return types.Data{}, false
+	}
+	from, ok := squareToIdx(s[0:2])
+	if !ok {
+		return types.Data{}, false
+	}
+	to, ok := squareToIdx(s[2:4])
+	if !ok {
+		return types.Data{}, false
+	}
+	move := types.Move{From: from, To: to}
+	if g.validateMove(&move) != nil {
+		return types.Data{}, false
+	}
+	if len(s) == 4 {
+		if g.isPromotion(&move) {
+			return types.Data{}, false
+		}
+		return types.Data{Type: types.MoveType, Data: move}, true
+	}
+	promoteTo, ok := promotedToFromByte(s[4])
+	if !ok || !g.isPromotion(&move) {
+		return types.Data{}, false
+	}
+	return types.Data{
+		Type: types.PromotionType,
+		Data: types.Promotion{Move: move, PromoteTo: promoteTo},
+	}, true
+}
+
+func (g *Game) parseSANMove(s string, original string) (types.Data, error) {
+	illegal := types.Data{Type: types.IllegalType, Data: nil}
+	if s == "O-O" || s == "0-0" || s == "O-O-O" || s == "0-0-0" {
+		return g.parseCastle(len(s) == 5, original)
+	}
+
+	piece := Piece(Pawn)
+	switch s[0] {
+	case 'N', 'B', 'R', 'Q', 'K':
+		piece, _ = pieceFromByte(s[0])
+		piece &= PIECEMASK
+		s = s[1:]
+		break
+	}
+
+	var promoteTo types.PromotedTo
+	hasPromotion := false
+	if piece == Pawn && len(s) > 2 {
+		last := s[len(s)-1]
+		hasEquals := s[len(s)-2] == '='
+		if last >= 'A' && last <= 'Z' {
+			last += 'a' - 'A'
+		} else if !hasEquals {
+			last = 0
+		}
+		if p, ok := promotedToFromByte(last); ok {
+			promoteTo = p
+			hasPromotion = true
+			s = strings.TrimSuffix(s[:len(s)-1], "=")
+		}
+	}
+
+	if len(s) < 2 {
+		return illegal, fmt.Errorf("invalid move: %q", original)
+	}
+	to, ok := squareToIdx(s[len(s)-2:])
+	if !ok {
+		return illegal, fmt.Errorf("invalid destination square in move: %q", original)
+	}
+	disambiguation := strings.Replace(s[:len(s)-2], "x", "", 1)
+	if !validDisambiguation(disambiguation) {
+		return illegal, fmt.Errorf("invalid move: %q", original)
+	}
+	if piece == Pawn && disambiguation == "" {
+		// a pawn only leaves its file when capturing,
+		// and captures always name the file it came from
+		disambiguation = idxToSquare(to)[:1]
+	}
+
+	var candidates []int
+	for _, m := range g.moves.moves[:g.moves.n] {
+		from := m.From()
+		if m.To() != to || g.board[from]&PIECEMASK != piece {
+			continue
+		}
+		// each promotion piece is its own move
+		if m.IsPromotion() && m.PromoteTo() != Queen {
+			continue
+		}
+		if !matchesDisambiguation(from, disambiguation) {
+			continue
+		}
+		candidates = append(candidates, from)
+	}
+	if len(candidates) == 0 {
+		return illegal, fmt.Errorf("illegal move: %s", original)
+	}
+	if len(candidates) > 1 {
+		return illegal, fmt.Errorf("ambiguous move: %s", original)
+	}
+
+	move := types.Move{From: candidates[0], To: to}
+	if g.isPromotion(&move) {
+		if !hasPromotion {
+			return illegal, fmt.Errorf("move %s must say which piece to promote to", original)
+		}
+		return types.Data{
+			Type: types.PromotionType,
+			Data: types.Promotion{Move: move, PromoteTo: promoteTo},
+		}, nil
+	}
+	if hasPromotion {
+		return illegal, fmt.Errorf("move %s is not a promotion", original)
+	}
+	return types.Data{Type: types.MoveType, Data: move}, nil
+}
+
+func (g *Game) parseCastle(queenSide bool, original string) (types.Data, error) {
+	king := 60
+	if g.toMove == 'b' {
+		king = 4
+	}
+	to := king + 2
+	if queenSide {
+		to = king - 2
+	}
+	move := types.Move{From: king, To: to}
+	if g.board[king] != King|g.colorToMove() || g.validateMove(&move) != nil {
+		return types.Data{Type: types.IllegalType, Data: nil}, fmt.Errorf("illegal move: %s", original)
+	}
+	return types.Data{Type: types.MoveType, Data: move}, nil
+}
+
+func validDisambiguation(disambiguation string) bool {
+	switch len(disambiguation) {
+	case 0:
+		return true
+	case 1:
+		ch := disambiguation[0]
+		return (ch >= 'a' && ch <= 'h') || (ch >= '1' && ch <= '8')
+	case 2:
+		_, ok := squareToIdx(disambiguation)
+		return ok
+	}
+	return false
+}
+
+func matchesDisambiguation(from int, disambiguation string) bool {
+	square := idxToSquare(from)
+	for i := 0; i < len(disambiguation); i++ {
+		ch := disambiguation[i]
+		if ch >= 'a' && ch <= 'h' && ch != square[0] {
+			return false
+		}
+		if ch >= '1' && ch <= '8' && ch != square[1] {
+			return false
+		}
+	}
+	return true
+}
+
+func promotedToFromByte(b byte) (types.PromotedTo, bool) {
+	switch b {
+	case 'n':
+		return types.KnightPromotion, true
+	case 'b':
+		return types.BishopPromotion, true
+	case 'r':
+		return types.RookPromotion, true
+	case 'q':
+		return types.QueenPromotion, true
+	}
+	return 0, false
+}
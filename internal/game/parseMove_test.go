@@ -0,0 +1,102 @@
+package game
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/vincer2040/chess/internal/types"
+)
+
+func TestParseMove(t *testing.T) {
+	tests := []struct {
+		fen       string
+		move      string
+		from      int
+		to        int
+		promoteTo types.PromotedTo
+		promotion bool
+	}{
+		{STARTING_POSITION, "e4", 52, 36, 0, false},
+		{STARTING_POSITION, "e2e4", 52, 36, 0, false},
+		{STARTING_POSITION, "Nf3", 62, 45, 0, false},
+		{STARTING_POSITION, "g1f3", 62, 45, 0, false},
+		{"4k3/8/8/3p4/4P3/8/8/4K3 w - - 0 1", "exd5", 36, 27, 0, false},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "exd6 e.p.", 28, 19, 0, false},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "O-O", 60, 62, 0, false},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "0-0-0", 60, 58, 0, false},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "e8g8", 4, 6, 0, false},
+		{"4k3/8/8/8/8/8/8/1N2KN2 w - - 0 1", "Nbd2", 57, 51, 0, false},
+		{"4k3/8/8/R7/8/8/8/R3K3 w - - 0 1", "R1a3", 56, 40, 0, false},
+		{"4k3/8/8/8/8/Q7/8/Q1Q1K3 w - - 0 1", "Qa1b2", 56, 49, 0, false},
+		{"4k3/8/8/8/8/8/8/R3K3 w - - 0 1", "Ra8+", 56, 0, 0, false},
+		{"8/1P2k3/8/8/8/8/8/4K3 w - - 0 1", "b8=Q", 9, 1, types.QueenPromotion, true},
+		{"8/1P2k3/8/8/8/8/8/4K3 w - - 0 1", "b8N", 9, 1, types.KnightPromotion, true},
+		{"8/1P2k3/8/8/8/8/8/4K3 w - - 0 1", "b7b8r", 9, 1, types.RookPromotion, true},
+		{"r3k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "bxa8=B+", 9, 0, types.BishopPromotion, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.move, func(t *testing.T) {
+			g := New(tt.fen)
+			data, err := g.ParseMove(tt.move)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var move types.Move
+			if tt.promotion {
+				if data.Type != types.PromotionType {
+					t.Fatalf("expected a promotion, got %+v", data)
+				}
+				promotion := data.Data.(types.Promotion)
+				if promotion.PromoteTo != tt.promoteTo {
+					t.Errorf("expected promotion to %d, got %d", tt.promoteTo, promotion.PromoteTo)
+				}
+				move = promotion.Move
+			} else {
+				if data.Type != types.MoveType {
+					t.Fatalf("expected a move, got %+v", data)
+				}
+				move = data.Data.(types.Move)
+			}
+			if move.From != tt.from || move.To != tt.to {
+				t.Errorf("expected %d to %d, got %d to %d", tt.from, tt.to, move.From, move.To)
+			}
+		})
+	}
+}
+
+func TestParseMoveErrors(t *testing.T) {
+	tests := []struct {
+		fen  string
+		move string
+		err  string
+	}{
+		{STARTING_POSITION, "", "empty move"},
+		{STARTING_POSITION, "e5", "illegal move"},
+		{STARTING_POSITION, "Ke2", "illegal move"},
+		{STARTING_POSITION, "e2e5", "illegal move"},
+		{STARTING_POSITION, "Nz3", "invalid"},
+		{STARTING_POSITION, "O-O", "illegal move"},
+		{"4k3/8/8/8/8/8/8/1N2KN2 w - - 0 1", "Nd2", "ambiguous move"},
+		{"4k3/8/8/R7/8/8/8/R3K3 w - - 0 1", "Ra3", "ambiguous move"},
+		{"8/1P2k3/8/8/8/8/8/4K3 w - - 0 1", "b8", "must say which piece"},
+		{"8/1P2k3/8/8/8/8/8/4K3 w - - 0 1", "b7b8", "must say which piece"},
+		{STARTING_POSITION, "e4=Q", "not a promotion"},
+		{"4r2k/8/8/8/8/8/4N3/1N2K3 w - - 0 1", "Nec3", "illegal move"},
+		{"R5k1/5ppp/8/8/8/8/8/6K1 b - - 1 1", "h6", "game is over"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.move, func(t *testing.T) {
+			g := New(tt.fen)
+			data, err := g.ParseMove(tt.move)
+			if err == nil {
+				t.Fatalf("expected an error, got %+v", data)
+			}
+			if !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("expected an error containing %q, got %q", tt.err, err)
+			}
+			if data.Type != types.IllegalType {
+				t.Errorf("expected an illegal move, got %+v", data)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package game
+
+// Perft counts the leaf nodes of the legal move tree
+// to the given depth, for checking the move generator
+func (g *Game) Perft(depth int) uint64 {
+	if depth == 0 {
+		return 1
+	}
+	if depth == 1 {
+		return uint64(g.moves.Len())
+	}
+	moves := g.moves
+	var nodes uint64
+	for _, m := range moves.moves[:moves.n] {
+		g.play(m)
+		nodes += g.Perft(depth - 1)
+		g.UnmakeMove()
+	}
+	return nodes
+}
+
+// Divide is Perft split up by the first move, keyed by
+// the move in uci notation
+func (g *Game) Divide(depth int) map[string]uint64 {
+	res := make(map[string]uint64)
+	if depth == 0 {
+		return res
+	}
+	moves := g.moves
+	for _, m := range moves.moves[:moves.n] {
+		g.play(m)
+		res[m.String()] = g.Perft(depth - 1)
+		g.UnmakeMove()
+	}
+	return res
+}
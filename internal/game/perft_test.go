@@ -0,0 +1,92 @@
+package game
+
+import (
+	"testing"
+)
+
+type perftTest struct {
+	name  string
+	fen   string
+	nodes []uint64
+}
+
+// https://www.chessprogramming.org/Perft_Results
+var perftTests = []perftTest{
+	{
+		name:  "initial",
+		fen:   "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		nodes: []uint64{20, 400, 8902, 197281, 4865609},
+	},
+	{
+		name:  "kiwipete",
+		fen:   "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		nodes: []uint64{48, 2039, 97862, 4085603},
+	},
+	{
+		name:  "position 3",
+		fen:   "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+		nodes: []uint64{14, 191, 2812, 43238, 674624},
+	},
+	{
+		name:  "position 4",
+		fen:   "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+		nodes: []uint64{6, 264, 9467, 422333},
+	},
+	{
+		name:  "position 4 mirrored",
+		fen:   "r2q1rk1/pP1p2pp/Q4n2/bbp1p3/Np6/1B3NBn/pPPP1PPP/R3K2R b KQ - 0 1",
+		nodes: []uint64{6, 264, 9467, 422333},
+	},
+	{
+		name:  "position 5",
+		fen:   "rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8",
+		nodes: []uint64{44, 1486, 62379, 2103487},
+	},
+	{
+		name:  "position 6",
+		fen:   "r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10",
+		nodes: []uint64{46, 2079, 89890, 3894594},
+	},
+}
+
+func TestPerft(t *testing.T) {
+	for _, tt := range perftTests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := Parse(tt.fen)
+			if err != nil {
+				t.Fatalf("failed to parse fen: %v", err)
+			}
+			for i, expected := range tt.nodes {
+				depth := i + 1
+				if testing.Short() && expected > 10000 {
+					break
+				}
+				got := g.Perft(depth)
+				if got != expected {
+					t.Errorf("depth %d: expected %d nodes, got %d", depth, expected, got)
+				}
+			}
+			if g.FEN() != tt.fen {
+				t.Errorf("perft did not restore the position, got %s", g.FEN())
+			}
+		})
+	}
+}
+
+func TestDivide(t *testing.T) {
+	g := New(STARTING_POSITION)
+	divide := g.Divide(2)
+	if len(divide) != 20 {
+		t.Fatalf("expected 20 moves, got %d", len(divide))
+	}
+	var total uint64
+	for move, nodes := range divide {
+		if nodes != 20 {
+			t.Errorf("%s: expected 20 nodes, got %d", move, nodes)
+		}
+		total += nodes
+	}
+	if total != g.Perft(2) {
+		t.Errorf("divide total %d does not match perft %d", total, g.Perft(2))
+	}
+}
@@ -2,6 +2,8 @@ package game
 
 import (
 	"unicode"
+
+	"github.com/vincer2040/chess/internal/types"
 )
 
 const (
@@ -22,37 +24,36 @@ const (
 
 type Piece byte
 
-func newPiece(p byte) Piece {
+func pieceFromByte(p byte) (Piece, bool) {
 	switch p {
 	case ' ':
-		return None
+		return None, true
 	case 'P':
-		return Pawn | White
+		return Pawn | White, true
 	case 'N':
-		return Knight | White
+		return Knight | White, true
 	case 'B':
-		return Bishop | White
+		return Bishop | White, true
 	case 'R':
-		return Rook | White
+		return Rook | White, true
 	case 'Q':
-		return Queen | White
+		return Queen | White, true
 	case 'K':
-		return King | White
+		return King | White, true
 	case 'p':
-		return Pawn | Black
+		return Pawn | Black, true
 	case 'n':
-		return Knight | Black
+		return Knight | Black, true
 	case 'b':
-		return Bishop | Black
+		return Bishop | Black, true
 	case 'r':
-		return Rook | Black
+		return Rook | Black, true
 	case 'q':
-		return Queen | Black
+		return Queen | Black, true
 	case 'k':
-		return King | Black
+		return King | Black, true
 	}
-
-	panic("unknown piece")
+	return None, false
 }
 
 func (p Piece) GetPieceByte() byte {
@@ -80,3 +81,29 @@ func (p Piece) GetPieceByte() byte {
 	}
 	return b
 }
+
+func promotedToPiece(promoteTo types.PromotedTo) (Piece, bool) {
+	switch promoteTo {
+	case types.KnightPromotion:
+		return Knight, true
+	case types.BishopPromotion:
+		return Bishop, true
+	case types.RookPromotion:
+		return Rook, true
+	case types.QueenPromotion:
+		return Queen, true
+	}
+	return None, false
+}
+
+func pieceToPromotedTo(piece Piece) types.PromotedTo {
+	switch piece & PIECEMASK {
+	case Knight:
+		return types.KnightPromotion
+	case Bishop:
+		return types.BishopPromotion
+	case Rook:
+		return types.RookPromotion
+	}
+	return types.QueenPromotion
+}
@@ -0,0 +1,146 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vincer2040/chess/internal/types"
+)
+
+func (g *Game) MoveSAN(move *types.Move) (string, error) {
+	err := g.validateMove(move)
+	if err != nil {
+		return "", err
+	}
+	if g.isPromotion(move) {
+		return "", fmt.Errorf("move %s%s must be a promotion", idxToSquare(move.From), idxToSquare(move.To))
+	}
+	san := g.sanWithoutSuffix(move, None)
+	isEnPassant := g.isEnPassant(move)
+	err = g.MakeMove(move)
+	if err != nil {
+		return "", err
+	}
+	san += g.checkSuffix()
+	g.UnmakeMove()
+	if isEnPassant {
+		san += " e.p."
+	}
+	return san, nil
+}
+
+func (g *Game) PromotionSAN(promotion *types.Promotion) (string, error) {
+	err := g.validateMove(&promotion.Move)
+	if err != nil {
+		return "", err
+	}
+	promotedTo, ok := promotedToPiece(promotion.PromoteTo)
+	if !ok {
+		return "", fmt.Errorf("unknown promotion piece: %d", promotion.PromoteTo)
+	}
+	san := g.sanWithoutSuffix(&promotion.Move, promotedTo)
+	err = g.MakePromotion(promotion)
+	if err != nil {
+		return "", err
+	}
+	san += g.checkSuffix()
+	g.UnmakeMove()
+	return san, nil
+}
+
+// sanWithoutSuffix builds the san for a legal move without
+// the check or checkmate marker, which needs the move played
+func (g *Game) sanWithoutSuffix(move *types.Move, promotedTo Piece) string {
+	var sb strings.Builder
+	piece := g.board[move.From] & PIECEMASK
+	isCapture := g.board.hasPieceOnIdx(move.To) || g.isEnPassant(move)
+	if piece == King && (move.To-move.From == 2 || move.From-move.To == 2) {
+		if move.To > move.From {
+			return "O-O"
+		}
+		return "O-O-O"
+	}
+	if piece == Pawn {
+		if isCapture {
+			sb.WriteByte(idxToSquare(move.From)[0])
+			sb.WriteByte('x')
+		}
+		sb.WriteString(idxToSquare(move.To))
+		if promotedTo != None {
+			sb.WriteByte('=')
+			sb.WriteByte(pieceLetter(promotedTo))
+		}
+		return sb.String()
+	}
+	sb.WriteByte(pieceLetter(piece))
+	sb.WriteString(g.disambiguation(move))
+	if isCapture {
+		sb.WriteByte('x')
+	}
+	sb.WriteString(idxToSquare(move.To))
+	return sb.String()
+}
+
+func (g *Game) disambiguation(move *types.Move) string {
+	piece := g.board[move.From]
+	sameFile := false
+	sameRank := false
+	ambiguous := false
+	for _, m := range g.moves.moves[:g.moves.n] {
+		from := m.From()
+		if from == move.From || m.To() != move.To || g.board[from] != piece {
+			continue
+		}
+		ambiguous = true
+		if getFileForIdx(from) == getFileForIdx(move.From) {
+			sameFile = true
+		}
+		if getRankForIdx(from) == getRankForIdx(move.From) {
+			sameRank = true
+		}
+	}
+	square := idxToSquare(move.From)
+	if !ambiguous {
+		return ""
+	}
+	if !sameFile {
+		return square[:1]
+	}
+	if !sameRank {
+		return square[1:]
+	}
+	return square
+}
+
+func (g *Game) checkSuffix() string {
+	if g.status == WhiteWinsByCheckmate || g.status == BlackWinsByCheckmate {
+		return "#"
+	}
+	if g.InCheck() {
+		return "+"
+	}
+	return ""
+}
+
+func (g *Game) isEnPassant(move *types.Move) bool {
+	if g.board[move.From]&PIECEMASK != Pawn || g.board.hasPieceOnIdx(move.To) {
+		return false
+	}
+	return getFileForIdx(move.From) != getFileForIdx(move.To)
+}
+
+func pieceLetter(piece Piece) byte {
+	switch piece & PIECEMASK {
+	case Knight:
+		return 'N'
+	case Bishop:
+		return 'B'
+	case Rook:
+		return 'R'
+	case Queen:
+		return 'Q'
+	case King:
+		return 'K'
+	}
+	return 'P'
+}
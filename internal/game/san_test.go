@@ -0,0 +1,69 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/vincer2040/chess/internal/types"
+)
+
+// uciSAN writes the move given in uci notation as san
+func uciSAN(t *testing.T, g *Game, uci string) string {
+	from, ok := squareToIdx(uci[0:2])
+	if !ok {
+		t.Fatalf("invalid square in %s", uci)
+	}
+	to, ok := squareToIdx(uci[2:4])
+	if !ok {
+		t.Fatalf("invalid square in %s", uci)
+	}
+	move := types.Move{From: from, To: to}
+	var san string
+	var err error
+	if len(uci) == 5 {
+		promoteTo, _ := promotedToFromByte(uci[4])
+		san, err = g.PromotionSAN(&types.Promotion{Move: move, PromoteTo: promoteTo})
+	} else {
+		san, err = g.MoveSAN(&move)
+	}
+	if err != nil {
+		t.Fatalf("%s: %v", uci, err)
+	}
+	return san
+}
+
+func TestSAN(t *testing.T) {
+	tests := []struct {
+		name     string
+		fen      string
+		move     string
+		expected string
+	}{
+		{"pawn push", STARTING_POSITION, "e2e4", "e4"},
+		{"knight", STARTING_POSITION, "g1f3", "Nf3"},
+		{"file disambiguation", "4k3/8/8/8/8/8/8/1N2KN2 w - - 0 1", "b1d2", "Nbd2"},
+		{"rank disambiguation", "4k3/8/8/R7/8/8/8/R3K3 w - - 0 1", "a1a3", "R1a3"},
+		{"square disambiguation", "4k3/8/8/8/8/Q7/8/Q1Q1K3 w - - 0 1", "a1b2", "Qa1b2"},
+		{"pinned piece needs no disambiguation", "4r2k/8/8/8/8/8/4N3/1N2K3 w - - 0 1", "b1c3", "Nc3"},
+		{"capture", "4k3/8/8/3p4/4P3/8/8/4K3 w - - 0 1", "e4d5", "exd5"},
+		{"piece capture", "4k3/8/8/3p4/8/4N3/8/4K3 w - - 0 1", "e3d5", "Nxd5"},
+		{"en passant", "4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "e5d6", "exd6 e.p."},
+		{"kingside castle", "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1g1", "O-O"},
+		{"queenside castle", "r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "e8c8", "O-O-O"},
+		{"promotion", "8/1P2k3/8/8/8/8/8/4K3 w - - 0 1", "b7b8q", "b8=Q"},
+		{"under-promotion", "8/1P2k3/8/8/8/8/8/4K3 w - - 0 1", "b7b8n", "b8=N"},
+		{"capturing promotion with check", "r3k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7a8r", "bxa8=R+"},
+		{"check", "4k3/8/8/8/8/8/8/R3K3 w - - 0 1", "a1a8", "Ra8+"},
+		{"checkmate", "6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", "a1a8", "Ra8#"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New(tt.fen)
+			if got := uciSAN(t, &g, tt.move); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+			if g.FEN() != tt.fen {
+				t.Errorf("the position was not restored, got %s", g.FEN())
+			}
+		})
+	}
+}
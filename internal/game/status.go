@@ -0,0 +1,98 @@
+package game
+
+type Status int
+
+const (
+	Ongoing Status = iota
+	WhiteWinsByCheckmate
+	BlackWinsByCheckmate
+	Stalemate
+	DrawByThreefoldRepetition
+	DrawByFivefoldRepetition
+	DrawByFiftyMoveRule
+	DrawBySeventyFiveMoveRule
+	DrawByInsufficientMaterial
+	WhiteWinsOnTime
+	BlackWinsOnTime
+	DrawByTimeoutVsInsufficientMaterial
+)
+
+func (s Status) IsOver() bool {
+	return s != Ongoing
+}
+
+func (s Status) Result() string {
+	switch s {
+	case WhiteWinsByCheckmate, WhiteWinsOnTime:
+		return "1-0"
+	case BlackWinsByCheckmate, BlackWinsOnTime:
+		return "0-1"
+	case Stalemate, DrawByThreefoldRepetition, DrawByFivefoldRepetition, DrawByFiftyMoveRule, DrawBySeventyFiveMoveRule, DrawByInsufficientMaterial, DrawByTimeoutVsInsufficientMaterial:
+		return "1/2-1/2"
+	}
+	return "*"
+}
+
+func (s Status) String() string {
+	switch s {
+	case Ongoing:
+		return "ongoing"
+	case WhiteWinsByCheckmate:
+		return "white wins by checkmate"
+	case BlackWinsByCheckmate:
+		return "black wins by checkmate"
+	case Stalemate:
+		return "stalemate"
+	case DrawByThreefoldRepetition:
+		return "draw by threefold repetition"
+	case DrawByFivefoldRepetition:
+		return "draw by fivefold repetition"
+	case DrawByFiftyMoveRule:
+		return "draw by the fifty move rule"
+	case DrawBySeventyFiveMoveRule:
+		return "draw by the seventy-five move rule"
+	case DrawByInsufficientMaterial:
+		return "draw by insufficient material"
+	case WhiteWinsOnTime:
+		return "white wins on time"
+	case BlackWinsOnTime:
+		return "black wins on time"
+	case DrawByTimeoutVsInsufficientMaterial:
+		return "draw by timeout vs insufficient material"
+	}
+	return "unknown"
+}
+
+func (g *Game) Status() Status {
+	return g.status
+}
+
+func (g *Game) InCheck() bool {
+	return g.kingAttacked(colorIndex(g.colorToMove()))
+}
+
+func (g *Game) updateStatus() {
+	if g.moves.Len() == 0 {
+		if !g.InCheck() {
+			g.status = Stalemate
+		} else if g.toMove == 'w' {
+			g.status = BlackWinsByCheckmate
+		} else {
+			g.status = WhiteWinsByCheckmate
+		}
+		return
+	}
+	if g.isDeadPosition() {
+		g.status = DrawByInsufficientMaterial
+		return
+	}
+	if g.Repetitions() >= 5 {
+		g.status = DrawByFivefoldRepetition
+		return
+	}
+	if g.halfmoveClock >= 150 {
+		g.status = DrawBySeventyFiveMoveRule
+		return
+	}
+	g.status = Ongoing
+}
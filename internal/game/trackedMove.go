@@ -2,6 +2,8 @@ package game
 
 import (
 	"math"
+
+	"github.com/vincer2040/chess/internal/types"
 )
 
 type TrackedMove struct {
@@ -11,6 +13,14 @@ type TrackedMove struct {
 	To          int
 	IsPromotion bool
 	PromoteTo   Piece
+
+	// the state before this move was made
+	// so that it can be taken back
+	prevCastleRights  CastleRights
+	prevEnPassant     int
+	prevHalfmoveClock int
+	prevStatus        Status
+	prevHash          uint64
 }
 
 func newTrackedMove(piece, captured Piece, from, to int, isPromotion bool, promoteTo Piece) TrackedMove {
@@ -24,6 +34,17 @@ func newTrackedMove(piece, captured Piece, from, to int, isPromotion bool, promo
 	}
 }
 
+func (tm *TrackedMove) Data() types.Data {
+	move := types.Move{From: tm.From, To: tm.To}
+	if !tm.IsPromotion {
+		return types.Data{Type: types.MoveType, Data: move}
+	}
+	return types.Data{
+		Type: types.PromotionType,
+		Data: types.Promotion{Move: move, PromoteTo: pieceToPromotedTo(tm.PromoteTo)},
+	}
+}
+
 func (tm *TrackedMove) isCastle() bool {
 	piece := tm.Piece & PIECEMASK
 	if piece != King {
@@ -62,20 +83,12 @@ func (tm *TrackedMove) isEnPassant() bool {
 	return amtMoved == 7 || amtMoved == 9
 }
 
-func (tm *TrackedMove) disablesCastle(castleRights *CastleRights) (bool, []DisabledCastleDirection) {
+// disablesCastle appends the castle rights this move takes away
+// to disabled. callers pass a buffer with room for all four so
+// that making a move doesn't allocate
+func (tm *TrackedMove) disablesCastle(disabled []DisabledCastleDirection) []DisabledCastleDirection {
 	color := tm.Piece & COLORMASK
 	piece := tm.Piece & PIECEMASK
-	disabled := make([]DisabledCastleDirection, 0)
-
-	if color == White {
-		if !castleRights.WhiteKing && !castleRights.WhiteQueen {
-			return false, disabled
-		}
-	} else {
-		if !castleRights.BlackKing && !castleRights.BlackQueen {
-			return false, disabled
-		}
-	}
 
 	if piece == King {
 		if color == White {
@@ -86,50 +99,39 @@ func (tm *TrackedMove) disablesCastle(castleRights *CastleRights) (bool, []Disab
 	}
 
 	if piece == Rook {
-		switch tm.From {
-		case 56:
-			disabled = append(disabled, WhiteCastleQueen)
-			break
-		case 63:
-			disabled = append(disabled, WhiteCastleKing)
-			break
-		case 0:
-			disabled = append(disabled, BlackCastleQueen)
-			break
-		case 7:
-			disabled = append(disabled, BlackCastleKing)
-			break
-		}
+		disabled = appendRookCastle(disabled, tm.From)
 	}
 
-	if !tm.isCapture() {
-		if len(disabled) == 0 {
-			return false, disabled
-		}
-		return true, disabled
+	if tm.isCapture() && tm.Captured&PIECEMASK == Rook {
+		disabled = appendRookCastle(disabled, tm.To)
 	}
+	return disabled
+}
 
-	captured := tm.Captured & PIECEMASK
-	if captured == Rook {
-		switch tm.To {
-		case 56:
-			disabled = append(disabled, WhiteCastleQueen)
-			break
-		case 63:
-			disabled = append(disabled, WhiteCastleKing)
-			break
-		case 0:
-			disabled = append(disabled, BlackCastleQueen)
-			break
-		case 7:
-			disabled = append(disabled, BlackCastleKing)
-			break
-		}
+// appendRookCastle appends the castle right of a rook on its starting square
+func appendRookCastle(disabled []DisabledCastleDirection, idx int) []DisabledCastleDirection {
+	switch idx {
+	case 56:
+		disabled = append(disabled, WhiteCastleQueen)
+		break
+	case 63:
+		disabled = append(disabled, WhiteCastleKing)
+		break
+	case 0:
+		disabled = append(disabled, BlackCastleQueen)
+		break
+	case 7:
+		disabled = append(disabled, BlackCastleKing)
+		break
 	}
+	return disabled
+}
 
-	if len(disabled) == 0 {
-		return false, disabled
+// String is the move in uci notation
+func (tm *TrackedMove) String() string {
+	s := idxToSquare(tm.From) + idxToSquare(tm.To)
+	if tm.IsPromotion {
+		s += string(pieceLetter(tm.PromoteTo) + ('a' - 'A'))
 	}
-
-	return true, disabled
+	return s
 }
@@ -0,0 +1,70 @@
+package game
+
+var (
+	// indexed by Piece directly, which leaves some rows unused
+	zobristPieces      [King | Black + 1][64]uint64
+	zobristCastle      [4]uint64
+	zobristEnPassant   [8]uint64
+	zobristBlackToMove uint64
+)
+
+func init() {
+	// a fixed seed keeps hashes stable between runs,
+	// so they can be stored and compared later
+	var state uint64 = 0x9e3779b97f4a7c15
+	next := func() uint64 {
+		// xorshift64*
+		state ^= state >> 12
+		state ^= state << 25
+		state ^= state >> 27
+		return state * 0x2545f4914f6cdd1d
+	}
+	for _, color := range []Piece{White, Black} {
+		for piece := Piece(Pawn); piece <= King; piece++ {
+			for sq := 0; sq < 64; sq++ {
+				zobristPieces[piece|color][sq] = next()
+			}
+		}
+	}
+	for i := range zobristCastle {
+		zobristCastle[i] = next()
+	}
+	for i := range zobristEnPassant {
+		zobristEnPassant[i] = next()
+	}
+	zobristBlackToMove = next()
+}
+
+func (g *Game) Hash() uint64 {
+	return g.hash
+}
+
+// computeHash builds the hash from scratch. moves keep
+// it up to date incrementally after that
+func (g *Game) computeHash() uint64 {
+	var hash uint64
+	for sq, piece := range g.board {
+		if piece != None {
+			hash ^= zobristPieces[piece][sq]
+		}
+	}
+	if g.castleRights.WhiteKing {
+		hash ^= zobristCastle[WhiteCastleKing]
+	}
+	if g.castleRights.WhiteQueen {
+		hash ^= zobristCastle[WhiteCastleQueen]
+	}
+	if g.castleRights.BlackKing {
+		hash ^= zobristCastle[BlackCastleKing]
+	}
+	if g.castleRights.BlackQueen {
+		hash ^= zobristCastle[BlackCastleQueen]
+	}
+	if g.enPassant != -1 {
+		hash ^= zobristEnPassant[getFileForIdx(g.enPassant)]
+	}
+	if g.toMove == 'b' {
+		hash ^= zobristBlackToMove
+	}
+	return hash
+}
@@ -0,0 +1,61 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/vincer2040/chess/internal/types"
+)
+
+func checkHash(t *testing.T, g *Game, depth int) {
+	if g.hash != g.computeHash() {
+		t.Fatalf("incremental hash does not match for %s", g.FEN())
+	}
+	if depth == 0 {
+		return
+	}
+	moves := g.Moves()
+	for i := 0; i < moves.Len(); i++ {
+		g.play(moves.At(i))
+		checkHash(t, g, depth-1)
+		g.UnmakeMove()
+	}
+}
+
+func TestZobristIncremental(t *testing.T) {
+	for _, tt := range perftTests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New(tt.fen)
+			before := g.Hash()
+			checkHash(t, &g, 3)
+			if g.Hash() != before {
+				t.Errorf("hash was not restored after unmaking moves")
+			}
+		})
+	}
+}
+
+func TestZobristTransposition(t *testing.T) {
+	a := New(STARTING_POSITION)
+	b := New(STARTING_POSITION)
+	for _, m := range []string{"Nf3", "Nf6", "Nc3", "Nc6"} {
+		data, _ := a.ParseMove(m)
+		move := data.Data.(types.Move)
+		a.MakeMove(&move)
+	}
+	for _, m := range []string{"Nc3", "Nc6", "Nf3", "Nf6"} {
+		data, _ := b.ParseMove(m)
+		move := data.Data.(types.Move)
+		b.MakeMove(&move)
+	}
+	if a.Hash() != b.Hash() {
+		t.Errorf("transposed positions have different hashes")
+	}
+	c := New("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1")
+	d := New("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1")
+	if c.Hash() == d.Hash() {
+		t.Errorf("en passant square is not part of the hash")
+	}
+	if c.repetitionKey() != d.repetitionKey() {
+		t.Errorf("uncapturable en passant square should not affect repetition")
+	}
+}
@@ -0,0 +1,225 @@
+package pgn
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+type tokenType int
+
+const (
+	eofToken tokenType = iota
+	leftBracketToken
+	rightBracketToken
+	leftParenToken
+	rightParenToken
+	stringToken
+	symbolToken
+	periodToken
+	asteriskToken
+	nagToken
+	commentToken
+)
+
+type token struct {
+	typ    tokenType
+	value  string
+	line   int
+	column int
+}
+
+type lexer struct {
+	r      *bufio.Reader
+	line   int
+	column int
+	// position of the last rune read, so that it can be unread
+	prevLine   int
+	prevColumn int
+}
+
+func newLexer(r io.Reader) *lexer {
+	return &lexer{
+		r:      bufio.NewReader(r),
+		line:   1,
+		column: 0,
+	}
+}
+
+func (l *lexer) next() (token, error) {
+	for {
+		ch, err := l.readRune()
+		if err == io.EOF {
+			return token{typ: eofToken, line: l.line, column: l.column + 1}, nil
+		}
+		if err != nil {
+			return token{}, err
+		}
+		line, column := l.line, l.column
+		tok := token{line: line, column: column}
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\r' || ch == '\n':
+			continue
+		case ch == '%' && column == 1:
+			// escape mechanism, the rest of the line is ignored
+			_, err = l.readUntil('\n')
+			if err != nil && err != io.EOF {
+				return token{}, err
+			}
+			continue
+		case ch == '[':
+			tok.typ = leftBracketToken
+			return tok, nil
+		case ch == ']':
+			tok.typ = rightBracketToken
+			return tok, nil
+		case ch == '(':
+			tok.typ = leftParenToken
+			return tok, nil
+		case ch == ')':
+			tok.typ = rightParenToken
+			return tok, nil
+		case ch == '.':
+			tok.typ = periodToken
+			return tok, nil
+		case ch == '*':
+			tok.typ = asteriskToken
+			return tok, nil
+		case ch == '"':
+			value, err := l.readString()
+			if err != nil {
+				return token{}, newSyntaxError(line, column, "unterminated string")
+			}
+			tok.typ = stringToken
+			tok.value = value
+			return tok, nil
+		case ch == '{':
+			value, err := l.readUntil('}')
+			if err != nil {
+				return token{}, newSyntaxError(line, column, "unterminated comment")
+			}
+			tok.typ = commentToken
+			tok.value = strings.TrimSpace(value)
+			return tok, nil
+		case ch == ';':
+			value, err := l.readUntil('\n')
+			if err != nil && err != io.EOF {
+				return token{}, err
+			}
+			tok.typ = commentToken
+			tok.value = strings.TrimSpace(value)
+			return tok, nil
+		case ch == '$':
+			value, err := l.readWhile(isDigit)
+			if err != nil {
+				return token{}, err
+			}
+			if value == "" {
+				return token{}, newSyntaxError(line, column, "expected a number after $")
+			}
+			tok.typ = nagToken
+			tok.value = value
+			return tok, nil
+		case isSymbolStart(ch):
+			rest, err := l.readWhile(isSymbolContinuation)
+			if err != nil {
+				return token{}, err
+			}
+			tok.typ = symbolToken
+			tok.value = string(ch) + rest
+			return tok, nil
+		}
+		return token{}, newSyntaxError(line, column, "unexpected character %q", ch)
+	}
+}
+
+func (l *lexer) readRune() (rune, error) {
+	ch, _, err := l.r.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	l.prevLine, l.prevColumn = l.line, l.column
+	if ch == '\n' {
+		l.line++
+		l.column = 0
+	} else {
+		l.column++
+	}
+	return ch, nil
+}
+
+func (l *lexer) unreadRune() {
+	l.r.UnreadRune()
+	l.line, l.column = l.prevLine, l.prevColumn
+}
+
+func (l *lexer) readUntil(end rune) (string, error) {
+	var sb strings.Builder
+	for {
+		ch, err := l.readRune()
+		if err != nil {
+			return sb.String(), err
+		}
+		if ch == end {
+			return sb.String(), nil
+		}
+		sb.WriteRune(ch)
+	}
+}
+
+func (l *lexer) readWhile(pred func(rune) bool) (string, error) {
+	var sb strings.Builder
+	for {
+		ch, err := l.readRune()
+		if err == io.EOF {
+			return sb.String(), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if !pred(ch) {
+			l.unreadRune()
+			return sb.String(), nil
+		}
+		sb.WriteRune(ch)
+	}
+}
+
+func (l *lexer) readString() (string, error) {
+	var sb strings.Builder
+	for {
+		ch, err := l.readRune()
+		if err != nil {
+			return "", err
+		}
+		if ch == '"' {
+			return sb.String(), nil
+		}
+		if ch == '\\' {
+			ch, err = l.readRune()
+			if err != nil {
+				return "", err
+			}
+		}
+		sb.WriteRune(ch)
+	}
+}
+
+func isDigit(ch rune) bool {
+	return '0' <= ch && ch <= '9'
+}
+
+func isSymbolStart(ch rune) bool {
+	return ('a' <= ch && ch <= 'z') || ('A' <= ch && ch <= 'Z') || isDigit(ch)
+}
+
+func isSymbolContinuation(ch rune) bool {
+	if isSymbolStart(ch) {
+		return true
+	}
+	switch ch {
+	case '_', '+', '#', '=', ':', '-', '/', '!', '?':
+		return true
+	}
+	return false
+}
@@ -0,0 +1,402 @@
+package pgn
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/vincer2040/chess/internal/game"
+	"github.com/vincer2040/chess/internal/types"
+)
+
+type Game struct {
+	Tags Tags
+	// comments that come before the first move
+	Comments []string
+	Moves    []*Move
+	Result   string
+	// the position reached after playing the mainline
+	Position game.Game
+}
+
+type Move struct {
+	SAN        string
+	Data       types.Data
+	NAGs       []int
+	Comments   []string
+	Variations []*Variation
+}
+
+type Variation struct {
+	Comments []string
+	Moves    []*Move
+}
+
+type SyntaxError struct {
+	Line   int
+	Column int
+	Msg    string
+}
+
+func newSyntaxError(line, column int, format string, args ...interface{}) *SyntaxError {
+	return &SyntaxError{
+		Line:   line,
+		Column: column,
+		Msg:    fmt.Sprintf(format, args...),
+	}
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+type Reader struct {
+	lexer  *lexer
+	peeked *token
+	failed bool
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{lexer: newLexer(r)}
+}
+
+// Next reads the next game. it returns io.EOF once there are no
+// more games. after an error the reader skips ahead to the next
+// tag section, so the remaining games can still be read
+func (r *Reader) Next() (*Game, error) {
+	if r.failed {
+		err := r.skipToNextGame()
+		if err != nil {
+			return nil, err
+		}
+		r.failed = false
+	}
+	g, err := r.readGame()
+	if err != nil && err != io.EOF {
+		r.failed = true
+	}
+	return g, err
+}
+
+func ReadAll(r io.Reader) ([]*Game, error) {
+	var res []*Game
+	reader := NewReader(r)
+	for {
+		g, err := reader.Next()
+		if err == io.EOF {
+			return res, nil
+		}
+		if err != nil {
+			return res, err
+		}
+		res = append(res, g)
+	}
+}
+
+func (r *Reader) readGame() (*Game, error) {
+	tok, err := r.peek()
+	if err != nil {
+		return nil, err
+	}
+	if tok.typ == eofToken {
+		return nil, io.EOF
+	}
+
+	g := &Game{}
+	for tok.typ == leftBracketToken {
+		tag, err := r.readTag()
+		if err != nil {
+			return nil, err
+		}
+		g.Tags = append(g.Tags, tag)
+		tok, err = r.peek()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	fen := game.STARTING_POSITION
+	if value, ok := g.Tags.Get("FEN"); ok {
+		fen = value
+	}
+	g.Position, err = game.Parse(fen)
+	if err != nil {
+		return nil, newSyntaxError(tok.line, tok.column, "invalid FEN tag: %v", err)
+	}
+
+	for {
+		tok, err := r.peek()
+		if err != nil {
+			return nil, err
+		}
+		if tok.typ == leftBracketToken {
+			// leave the tag for the next game to pick up
+			return nil, newSyntaxError(tok.line, tok.column, "unexpected tag in movetext, expected a game termination marker")
+		}
+		r.next()
+		last := lastMove(g.Moves)
+		switch tok.typ {
+		case eofToken:
+			return nil, newSyntaxError(tok.line, tok.column, "unexpected end of file, expected a game termination marker")
+		case asteriskToken:
+			g.Result = "*"
+			return g, nil
+		case periodToken:
+			break
+		case commentToken:
+			if last == nil {
+				g.Comments = append(g.Comments, tok.value)
+			} else {
+				last.Comments = append(last.Comments, tok.value)
+			}
+			break
+		case nagToken:
+			if last == nil {
+				return nil, newSyntaxError(tok.line, tok.column, "annotation glyph before the first move")
+			}
+			nag, _ := strconv.Atoi(tok.value)
+			last.NAGs = append(last.NAGs, nag)
+			break
+		case leftParenToken:
+			if last == nil {
+				return nil, newSyntaxError(tok.line, tok.column, "variation before the first move")
+			}
+			variation, err := r.readVariation()
+			if err != nil {
+				return nil, err
+			}
+			last.Variations = append(last.Variations, variation)
+			break
+		case symbolToken:
+			if isResult(tok.value) {
+				g.Result = tok.value
+				return g, nil
+			}
+			if isMoveNumber(tok.value) {
+				break
+			}
+			move := newMove(tok.value)
+			move.Data, err = g.Position.ParseMove(move.SAN)
+			if err == nil {
+				err = play(&g.Position, move.Data)
+			}
+			if err != nil {
+				return nil, newSyntaxError(tok.line, tok.column, "%v", err)
+			}
+			g.Moves = append(g.Moves, move)
+			break
+		default:
+			return nil, newSyntaxError(tok.line, tok.column, "unexpected %s in movetext", tok.describe())
+		}
+	}
+}
+
+func (r *Reader) readTag() (Tag, error) {
+	r.next()
+	name, err := r.expect(symbolToken, "tag name")
+	if err != nil {
+		return Tag{}, err
+	}
+	value, err := r.expect(stringToken, "tag value")
+	if err != nil {
+		return Tag{}, err
+	}
+	_, err = r.expect(rightBracketToken, "]")
+	if err != nil {
+		return Tag{}, err
+	}
+	return Tag{Name: name.value, Value: value.value}, nil
+}
+
+// readVariation reads the moves of a variation up to its closing
+// parenthesis. they are only checked to look like moves, since
+// playing them out would need the position they branch from
+func (r *Reader) readVariation() (*Variation, error) {
+	v := &Variation{}
+	for {
+		tok, err := r.next()
+		if err != nil {
+			return nil, err
+		}
+		last := lastMove(v.Moves)
+		switch tok.typ {
+		case rightParenToken:
+			if len(v.Moves) == 0 {
+				return nil, newSyntaxError(tok.line, tok.column, "empty variation")
+			}
+			return v, nil
+		case eofToken:
+			return nil, newSyntaxError(tok.line, tok.column, "unexpected end of file, expected )")
+		case periodToken:
+			break
+		case commentToken:
+			if last == nil {
+				v.Comments = append(v.Comments, tok.value)
+			} else {
+				last.Comments = append(last.Comments, tok.value)
+			}
+			break
+		case nagToken:
+			if last == nil {
+				return nil, newSyntaxError(tok.line, tok.column, "annotation glyph before the first move")
+			}
+			nag, _ := strconv.Atoi(tok.value)
+			last.NAGs = append(last.NAGs, nag)
+			break
+		case leftParenToken:
+			if last == nil {
+				return nil, newSyntaxError(tok.line, tok.column, "variation before the first move")
+			}
+			variation, err := r.readVariation()
+			if err != nil {
+				return nil, err
+			}
+			last.Variations = append(last.Variations, variation)
+			break
+		case symbolToken:
+			if isMoveNumber(tok.value) {
+				break
+			}
+			if isResult(tok.value) {
+				return nil, newSyntaxError(tok.line, tok.column, "game termination marker inside a variation")
+			}
+			v.Moves = append(v.Moves, newMove(tok.value))
+			break
+		default:
+			return nil, newSyntaxError(tok.line, tok.column, "unexpected %s in variation", tok.describe())
+		}
+	}
+}
+
+func (r *Reader) skipToNextGame() error {
+	for {
+		tok, err := r.peek()
+		if err != nil {
+			if _, ok := err.(*SyntaxError); ok {
+				r.peeked = nil
+				continue
+			}
+			return err
+		}
+		if tok.typ == eofToken || (tok.typ == leftBracketToken && tok.column == 1) {
+			return nil
+		}
+		r.next()
+	}
+}
+
+func (r *Reader) peek() (token, error) {
+	if r.peeked != nil {
+		return *r.peeked, nil
+	}
+	tok, err := r.lexer.next()
+	if err != nil {
+		return token{}, err
+	}
+	r.peeked = &tok
+	return tok, nil
+}
+
+func (r *Reader) next() (token, error) {
+	tok, err := r.peek()
+	r.peeked = nil
+	return tok, err
+}
+
+func (r *Reader) expect(typ tokenType, what string) (token, error) {
+	tok, err := r.next()
+	if err != nil {
+		return token{}, err
+	}
+	if tok.typ != typ {
+		return token{}, newSyntaxError(tok.line, tok.column, "expected %s, got %s", what, tok.describe())
+	}
+	return tok, nil
+}
+
+func (t token) describe() string {
+	switch t.typ {
+	case eofToken:
+		return "end of file"
+	case leftBracketToken:
+		return "["
+	case rightBracketToken:
+		return "]"
+	case leftParenToken:
+		return "("
+	case rightParenToken:
+		return ")"
+	case stringToken:
+		return fmt.Sprintf("string %q", t.value)
+	case periodToken:
+		return "."
+	case asteriskToken:
+		return "*"
+	case nagToken:
+		return "$" + t.value
+	case commentToken:
+		return "comment"
+	}
+	return t.value
+}
+
+// newMove splits the suffix annotations ("!", "?!", ...) off
+// of a san move and records them as their equivalent glyph
+func newMove(symbol string) *Move {
+	san := strings.TrimRight(symbol, "!?")
+	move := &Move{SAN: san}
+	switch symbol[len(san):] {
+	case "!":
+		move.NAGs = append(move.NAGs, 1)
+		break
+	case "?":
+		move.NAGs = append(move.NAGs, 2)
+		break
+	case "!!":
+		move.NAGs = append(move.NAGs, 3)
+		break
+	case "??":
+		move.NAGs = append(move.NAGs, 4)
+		break
+	case "!?":
+		move.NAGs = append(move.NAGs, 5)
+		break
+	case "?!":
+		move.NAGs = append(move.NAGs, 6)
+		break
+	}
+	return move
+}
+
+func lastMove(moves []*Move) *Move {
+	if len(moves) == 0 {
+		return nil
+	}
+	return moves[len(moves)-1]
+}
+
+func isResult(s string) bool {
+	return s == "1-0" || s == "0-1" || s == "1/2-1/2"
+}
+
+func isMoveNumber(s string) bool {
+	for _, ch := range s {
+		if !isDigit(ch) {
+			return false
+		}
+	}
+	return true
+}
+
+func play(g *game.Game, data types.Data) error {
+	switch data.Type {
+	case types.MoveType:
+		move := data.Data.(types.Move)
+		return g.MakeMove(&move)
+	case types.PromotionType:
+		promotion := data.Data.(types.Promotion)
+		return g.MakePromotion(&promotion)
+	}
+	return fmt.Errorf("not a move: %+v", data)
+}
@@ -0,0 +1,161 @@
+package pgn
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+const twoGames = `[Event "Game \"one\""]
+[Site "C:\\chess"]
+[Result "1-0"]
+
+{opening comment} 1. e4 $1 e5!? ; line comment
+2. Nf3 (2. f4 exf4 (2... d5 $2) 3. Nf3 {gambit}) Nc6 {develops} 3. Bb5 a6 1-0
+
+[Event "two"]
+
+1. d4 d5 *
+`
+
+func TestReadAll(t *testing.T) {
+	games, err := ReadAll(strings.NewReader(twoGames))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(games) != 2 {
+		t.Fatalf("expected 2 games, got %d", len(games))
+	}
+
+	g := games[0]
+	if v, _ := g.Tags.Get("Event"); v != `Game "one"` {
+		t.Errorf("expected the escaped quotes to be read, got %q", v)
+	}
+	if v, _ := g.Tags.Get("Site"); v != `C:\chess` {
+		t.Errorf("expected the escaped backslash to be read, got %q", v)
+	}
+	if len(g.Comments) != 1 || g.Comments[0] != "opening comment" {
+		t.Errorf("expected the comment before the first move, got %q", g.Comments)
+	}
+	var sans []string
+	for _, m := range g.Moves {
+		sans = append(sans, m.SAN)
+	}
+	if strings.Join(sans, " ") != "e4 e5 Nf3 Nc6 Bb5 a6" {
+		t.Fatalf("unexpected mainline %v", sans)
+	}
+	if len(g.Moves[0].NAGs) != 1 || g.Moves[0].NAGs[0] != 1 {
+		t.Errorf("expected $1 on e4, got %v", g.Moves[0].NAGs)
+	}
+	if len(g.Moves[1].NAGs) != 1 || g.Moves[1].NAGs[0] != 5 {
+		t.Errorf("expected !? to be read as $5, got %v", g.Moves[1].NAGs)
+	}
+	if len(g.Moves[1].Comments) != 1 || g.Moves[1].Comments[0] != "line comment" {
+		t.Errorf("expected the ; comment on e5, got %q", g.Moves[1].Comments)
+	}
+	if len(g.Moves[3].Comments) != 1 || g.Moves[3].Comments[0] != "develops" {
+		t.Errorf("expected the comment on Nc6, got %q", g.Moves[3].Comments)
+	}
+	if g.Result != "1-0" {
+		t.Errorf("expected 1-0, got %s", g.Result)
+	}
+	if fen := g.Position.FEN(); fen != "r1bqkbnr/1ppp1ppp/p1n5/1B2p3/4P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 0 4" {
+		t.Errorf("unexpected final position %s", fen)
+	}
+
+	if len(g.Moves[2].Variations) != 1 {
+		t.Fatalf("expected a variation on Nf3, got %d", len(g.Moves[2].Variations))
+	}
+	v := g.Moves[2].Variations[0]
+	if len(v.Moves) != 3 || v.Moves[0].SAN != "f4" || v.Moves[2].SAN != "Nf3" {
+		t.Fatalf("unexpected variation %+v", v.Moves)
+	}
+	if len(v.Moves[2].Comments) != 1 || v.Moves[2].Comments[0] != "gambit" {
+		t.Errorf("expected the comment in the variation, got %q", v.Moves[2].Comments)
+	}
+	if len(v.Moves[1].Variations) != 1 {
+		t.Fatalf("expected a nested variation on exf4")
+	}
+	nested := v.Moves[1].Variations[0]
+	if len(nested.Moves) != 1 || nested.Moves[0].SAN != "d5" || len(nested.Moves[0].NAGs) != 1 || nested.Moves[0].NAGs[0] != 2 {
+		t.Errorf("unexpected nested variation %+v", nested.Moves[0])
+	}
+
+	g = games[1]
+	if g.Result != "*" || len(g.Moves) != 2 {
+		t.Errorf("unexpected second game: result %s with %d moves", g.Result, len(g.Moves))
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		pgn    string
+		line   int
+		column int
+		msg    string
+	}{
+		{"illegal move", "1. e4 e5 2. Ke3 *", 1, 13, "illegal move"},
+		{"unterminated comment", "[Event \"x\"]\n\n1. e4 {never closed", 3, 7, "unterminated comment"},
+		{"unterminated string", "[Event \"x]\n", 1, 8, "unterminated string"},
+		{"missing termination", "1. e4 e5\n", 2, 1, "unexpected end of file"},
+		{"unclosed variation", "1. e4 (1. d4\n", 2, 1, "expected )"},
+		{"bad tag", "[Event x]\n", 1, 8, "expected tag value"},
+		{"glyph before a move", "$1 1. e4 *", 1, 1, "annotation glyph"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewReader(strings.NewReader(tt.pgn)).Next()
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("expected a syntax error, got %v", err)
+			}
+			if syntaxErr.Line != tt.line || syntaxErr.Column != tt.column {
+				t.Errorf("expected line %d, column %d, got %s", tt.line, tt.column, err)
+			}
+			if !strings.Contains(syntaxErr.Msg, tt.msg) {
+				t.Errorf("expected %q in %q", tt.msg, syntaxErr.Msg)
+			}
+		})
+	}
+}
+
+func TestReadResyncs(t *testing.T) {
+	pgn := `[Event "a"]
+
+1. e4 *
+
+[Event "b"]
+
+1. e4 Ke7 2. Nf3 (2. d4
+ [not a tag] *
+
+[Event "c"]
+
+1. d4 *
+`
+	r := NewReader(strings.NewReader(pgn))
+	g, err := r.Next()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v, _ := g.Tags.Get("Event"); v != "a" {
+		t.Errorf("expected game a, got %q", v)
+	}
+	_, err = r.Next()
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) || syntaxErr.Line != 7 || syntaxErr.Column != 7 {
+		t.Fatalf("expected an error at line 7, column 7, got %v", err)
+	}
+	g, err = r.Next()
+	if err != nil {
+		t.Fatalf("expected to resync to the next game, got %v", err)
+	}
+	if v, _ := g.Tags.Get("Event"); v != "c" || len(g.Moves) != 1 {
+		t.Errorf("expected game c with one move, got %q with %d", v, len(g.Moves))
+	}
+	if _, err = r.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+}
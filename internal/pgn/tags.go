@@ -0,0 +1,37 @@
+package pgn
+
+type Tag struct {
+	Name  string
+	Value string
+}
+
+type Tags []Tag
+
+var sevenTagRoster = []Tag{
+	{Name: "Event", Value: "?"},
+	{Name: "Site", Value: "?"},
+	{Name: "Date", Value: "????.??.??"},
+	{Name: "Round", Value: "?"},
+	{Name: "White", Value: "?"},
+	{Name: "Black", Value: "?"},
+	{Name: "Result", Value: "*"},
+}
+
+func (t Tags) Get(name string) (string, bool) {
+	for _, tag := range t {
+		if tag.Name == name {
+			return tag.Value, true
+		}
+	}
+	return "", false
+}
+
+func (t Tags) Set(name, value string) Tags {
+	for i, tag := range t {
+		if tag.Name == name {
+			t[i].Value = value
+			return t
+		}
+	}
+	return append(t, Tag{Name: name, Value: value})
+}
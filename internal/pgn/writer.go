@@ -0,0 +1,138 @@
+package pgn
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/vincer2040/chess/internal/game"
+	"github.com/vincer2040/chess/internal/types"
+)
+
+const maxLineLength = 80
+
+// Write exports g as pgn. the seven tag roster is always written,
+// falling back to "?" for anything missing from tags, and the
+// result is taken from the game rather than from tags
+func Write(w io.Writer, g *game.Game, tags Tags) error {
+	result := g.Status().Result()
+	tokens, err := movetext(g)
+	if err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+	for _, tag := range sevenTagRoster {
+		value := tag.Value
+		if v, ok := tags.Get(tag.Name); ok {
+			value = v
+		}
+		if tag.Name == "Result" {
+			value = result
+		}
+		writeTag(&sb, tag.Name, value)
+	}
+	if g.StartingFEN() != game.STARTING_POSITION {
+		writeTag(&sb, "SetUp", "1")
+		writeTag(&sb, "FEN", g.StartingFEN())
+	}
+	for _, tag := range tags {
+		if isReservedTag(tag.Name) {
+			continue
+		}
+		writeTag(&sb, tag.Name, tag.Value)
+	}
+	sb.WriteByte('\n')
+
+	tokens = append(tokens, result)
+	lineLength := 0
+	for i, token := range tokens {
+		if i != 0 {
+			if lineLength+1+len(token) > maxLineLength {
+				sb.WriteByte('\n')
+				lineLength = 0
+			} else {
+				sb.WriteByte(' ')
+				lineLength++
+			}
+		}
+		sb.WriteString(token)
+		lineLength += len(token)
+	}
+	sb.WriteString("\n\n")
+
+	_, err = io.WriteString(w, sb.String())
+	return err
+}
+
+func String(g *game.Game, tags Tags) (string, error) {
+	var sb strings.Builder
+	err := Write(&sb, g, tags)
+	if err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// movetext replays the game from its starting position to
+// produce the san of every move along with move numbers
+func movetext(g *game.Game) ([]string, error) {
+	var res []string
+	replay, err := game.Parse(g.StartingFEN())
+	if err != nil {
+		return nil, err
+	}
+	for i, tm := range g.TrackedMoves() {
+		moveNumber := strconv.Itoa(replay.FullmoveNumber())
+		if replay.ToMove() == 'w' {
+			res = append(res, moveNumber+".")
+		} else if i == 0 {
+			res = append(res, moveNumber+"...")
+		}
+		data := tm.Data()
+		var san string
+		switch data.Type {
+		case types.MoveType:
+			move := data.Data.(types.Move)
+			san, err = replay.MoveSAN(&move)
+			if err != nil {
+				return nil, err
+			}
+			err = replay.MakeMove(&move)
+			break
+		case types.PromotionType:
+			promotion := data.Data.(types.Promotion)
+			san, err = replay.PromotionSAN(&promotion)
+			if err != nil {
+				return nil, err
+			}
+			err = replay.MakePromotion(&promotion)
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		// pgn does not allow the e.p. annotation in movetext
+		res = append(res, strings.TrimSuffix(san, " e.p."))
+	}
+	return res, nil
+}
+
+func writeTag(sb *strings.Builder, name, value string) {
+	value = strings.ReplaceAll(value, "\\", "\\\\")
+	value = strings.ReplaceAll(value, "\"", "\\\"")
+	sb.WriteString(fmt.Sprintf("[%s \"%s\"]\n", name, value))
+}
+
+func isReservedTag(name string) bool {
+	if name == "SetUp" || name == "FEN" {
+		return true
+	}
+	for _, tag := range sevenTagRoster {
+		if tag.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package pgn
+
+import (
+	"testing"
+
+	"github.com/vincer2040/chess/internal/game"
+)
+
+// playMoves makes each move on a new game from fen
+func playMoves(t *testing.T, fen string, moves ...string) *game.Game {
+	g, err := game.Parse(fen)
+	if err != nil {
+		t.Fatalf("failed to parse fen: %v", err)
+	}
+	for _, m := range moves {
+		data, err := g.ParseMove(m)
+		if err != nil {
+			t.Fatalf("%s: %v", m, err)
+		}
+		if err := play(&g, data); err != nil {
+			t.Fatalf("%s: %v", m, err)
+		}
+	}
+	return &g
+}
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		name     string
+		g        *game.Game
+		tags     Tags
+		expected string
+	}{
+		{
+			name: "seven tag roster order and result from the game",
+			g:    playMoves(t, game.STARTING_POSITION, "f3", "e5", "g4", "Qh4#"),
+			tags: Tags{
+				{Name: "Annotator", Value: "me"},
+				{Name: "Black", Value: "Bob"},
+				{Name: "Result", Value: "1-0"},
+				{Name: "White", Value: "Alice"},
+				{Name: "Event", Value: "Casual"},
+			},
+			expected: `[Event "Casual"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "Alice"]
+[Black "Bob"]
+[Result "0-1"]
+[Annotator "me"]
+
+1. f3 e5 2. g4 Qh4# 0-1
+
+`,
+		},
+		{
+			name: "set up position with black to move",
+			g:    playMoves(t, "4k3/8/8/8/8/8/4P3/4K3 b - - 0 12", "Kd7", "e4"),
+			expected: `[Event "?"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "?"]
+[Black "?"]
+[Result "*"]
+[SetUp "1"]
+[FEN "4k3/8/8/8/8/8/4P3/4K3 b - - 0 12"]
+
+12... Kd7 13. e4 *
+
+`,
+		},
+		{
+			name: "escaped tag values",
+			g:    playMoves(t, game.STARTING_POSITION),
+			tags: Tags{{Name: "Event", Value: `say "hi" \o/`}},
+			expected: `[Event "say \"hi\" \\o/"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "?"]
+[Black "?"]
+[Result "*"]
+
+*
+
+`,
+		},
+		{
+			name: "movetext wrapped at 80 columns",
+			g: playMoves(t, game.STARTING_POSITION,
+				"e4", "e5", "Nf3", "Nc6", "Bb5", "a6", "Ba4", "Nf6", "O-O", "Be7", "Re1",
+				"b5", "Bb3", "d6", "c3", "O-O", "h3", "Nb8", "d4", "Nbd7", "c4", "c6"),
+			expected: `[Event "?"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "?"]
+[Black "?"]
+[Result "*"]
+
+1. e4 e5 2. Nf3 Nc6 3. Bb5 a6 4. Ba4 Nf6 5. O-O Be7 6. Re1 b5 7. Bb3 d6 8. c3
+O-O 9. h3 Nb8 10. d4 Nbd7 11. c4 c6 *
+
+`,
+		},
+		{
+			name: "draw result",
+			g:    playMoves(t, "4k3/8/8/8/8/8/3p4/3NK3 w - - 0 1", "Kxd2"),
+			expected: `[Event "?"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "?"]
+[Black "?"]
+[Result "1/2-1/2"]
+[SetUp "1"]
+[FEN "4k3/8/8/8/8/8/3p4/3NK3 w - - 0 1"]
+
+1. Kxd2 1/2-1/2
+
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := String(tt.g, tt.tags)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", tt.expected, got)
+			}
+		})
+	}
+}
@@ -1,7 +1,9 @@
 package protocol
 
 import (
+	"sort"
 	"strconv"
+	"time"
 
 	"github.com/vincer2040/chess/internal/game"
 	"github.com/vincer2040/chess/internal/types"
@@ -28,7 +30,13 @@ func (b Builder) AddLegalMoves(legalMoves game.LegalMoves) Builder {
 		b = append(b, byte(ch))
 	}
 	b = b.addEnd()
-	for k, v := range legalMoves {
+	keys := make([]int, 0, len(legalMoves))
+	for k := range legalMoves {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	for _, k := range keys {
+		v := legalMoves[k]
 		// add the key
 		key := strconv.Itoa(k)
 		for _, ch := range key {
@@ -64,7 +72,13 @@ func (b Builder) AddAttackingMoves(attackingMoves game.AttackingMoves) Builder {
 		b = append(b, byte(ch))
 	}
 	b = b.addEnd()
-	for k, v := range attackingMoves {
+	keys := make([]int, 0, len(attackingMoves))
+	for k := range attackingMoves {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	for _, k := range keys {
+		v := attackingMoves[k]
 		// add key
 		key := strconv.Itoa(k)
 		for _, ch := range key {
@@ -151,6 +165,81 @@ func (b Builder) AddPromotion(promotion *types.Promotion) Builder {
 	return b.addEnd()
 }
 
+func (b Builder) AddResult(status game.Status) Builder {
+	b = append(b, RESULT_BYTE)
+	for _, ch := range status.Result() {
+		b = append(b, byte(ch))
+	}
+	b = append(b, SEPARATOR)
+	for _, ch := range status.String() {
+		b = append(b, byte(ch))
+	}
+	return b.addEnd()
+}
+
+func (b Builder) AddPGN(pgn string) Builder {
+	b = append(b, PGN_BYTE)
+	for _, ch := range []byte(pgn) {
+		b = append(b, ch)
+	}
+	return b.addEnd()
+}
+
+// AddEvaluation sends a score in centipawns from white's point of view
+func (b Builder) AddEvaluation(score int) Builder {
+	b = append(b, EVALUATION_BYTE)
+	for _, ch := range strconv.Itoa(score) {
+		b = append(b, byte(ch))
+	}
+	return b.addEnd()
+}
+
+// AddAnalysis sends a search result as depth:cp:score:pv, or
+// depth:mate:moves:pv once a mate is found. scores are from
+// white's point of view and the pv is uci moves split by spaces
+func (b Builder) AddAnalysis(depth, score, mate int, pv []string) Builder {
+	b = append(b, ANALYSIS_BYTE)
+	for _, ch := range strconv.Itoa(depth) {
+		b = append(b, byte(ch))
+	}
+	b = append(b, SEPARATOR)
+	kind, value := "cp", score
+	if mate != 0 {
+		kind, value = "mate", mate
+	}
+	for _, ch := range kind {
+		b = append(b, byte(ch))
+	}
+	b = append(b, SEPARATOR)
+	for _, ch := range strconv.Itoa(value) {
+		b = append(b, byte(ch))
+	}
+	b = append(b, SEPARATOR)
+	for i, m := range pv {
+		if i != 0 {
+			b = append(b, ' ')
+		}
+		b = append(b, m...)
+	}
+	return b.addEnd()
+}
+
+// AddClock sends the time both sides have left in milliseconds as
+// white:black:running, where running is the side whose time is
+// running or - while the clock is stopped
+func (b Builder) AddClock(white, black time.Duration, running byte) Builder {
+	b = append(b, CLOCK_BYTE)
+	for _, ch := range strconv.FormatInt(white.Milliseconds(), 10) {
+		b = append(b, byte(ch))
+	}
+	b = append(b, SEPARATOR)
+	for _, ch := range strconv.FormatInt(black.Milliseconds(), 10) {
+		b = append(b, byte(ch))
+	}
+	b = append(b, SEPARATOR, running)
+	return b.addEnd()
+}
+
 func (b Builder) AddCommand(command string) Builder {
 	b = append(b, COMMAND_BYTE)
 	for _, ch := range command {
@@ -17,10 +17,15 @@ const (
 	ERROR_BYTE     = '-'
 	PROMOTION_BYTE = '!'
 
-	// client should never contain these two:
+	// client should never contain these:
 	LEGAL_MOVES_BYTE     = '~'
 	ATTACKING_MOVES_BYTE = '^'
 	ARRAY_BYTE           = '*'
+	RESULT_BYTE          = '='
+	PGN_BYTE             = '%'
+	EVALUATION_BYTE      = '&'
+	ANALYSIS_BYTE        = '@'
+	CLOCK_BYTE           = '|'
 )
 
 type Parser struct {
@@ -0,0 +1,68 @@
+package routes
+
+import (
+	"errors"
+	"time"
+
+	"github.com/vincer2040/chess/internal/clock"
+	"github.com/vincer2040/chess/internal/game"
+	"github.com/vincer2040/chess/internal/protocol"
+)
+
+// parseClock handles the arguments of CLOCK, a control such as
+// 40/90+30,30+30 optionally followed by fischer, bronstein or delay
+func parseClock(args []string, g *game.Game) (*clock.Clock, error) {
+	if len(args) == 0 || len(args) > 2 {
+		return nil, errors.New("usage: CLOCK:<control>:<fischer|bronstein|delay>")
+	}
+	if len(g.TrackedMoves()) > 0 {
+		return nil, errors.New("the clock can only be set before the first move")
+	}
+	control, err := clock.ParseControl(args[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 2 {
+		control.Mode, err = clock.ParseMode(args[1])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return clock.New(control, g.ToMove()), nil
+}
+
+// flagFall ends g if the side to move has run out of time on clk
+func flagFall(g *game.Game, clk *clock.Clock, now time.Time) bool {
+	if clk == nil || g.Status().IsOver() || !clk.Flagged(now) {
+		return false
+	}
+	clk.Stop(now)
+	g.TimeOut()
+	return true
+}
+
+// pressClock ends the turn of whoever just moved on g
+func pressClock(g *game.Game, clk *clock.Clock, now time.Time) {
+	if clk == nil {
+		return
+	}
+	clk.Press(now)
+	if g.Status().IsOver() {
+		clk.Stop(now)
+	}
+}
+
+func addClock(b protocol.Builder, clk *clock.Clock, now time.Time) protocol.Builder {
+	var running byte = '-'
+	if clk.Running() {
+		running = clk.ToMove()
+	}
+	white := max(clk.Remaining('w', now), 0)
+	black := max(clk.Remaining('b', now), 0)
+	return b.AddClock(white, black, running)
+}
+
+// thinkingTime is how long the computer gives a move with remaining left
+func thinkingTime(remaining time.Duration) time.Duration {
+	return max(remaining/30, 10*time.Millisecond)
+}
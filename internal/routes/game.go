@@ -2,10 +2,14 @@ package routes
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/labstack/echo/v4"
+	"github.com/vincer2040/chess/internal/clock"
 	"github.com/vincer2040/chess/internal/game"
+	"github.com/vincer2040/chess/internal/pgn"
 	"github.com/vincer2040/chess/internal/protocol"
 	"github.com/vincer2040/chess/internal/types"
 )
@@ -15,12 +19,15 @@ var (
 )
 
 func GameGet(c echo.Context) error {
-	g := game.New("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
 	ws, err := upgrader.Upgrade(c.Response(), c.Request(), nil)
 	if err != nil {
 		return err
 	}
 	defer ws.Close()
+	s := newSession(ws, c.Logger())
+	defer func() {
+		s.detach(ws)
+	}()
 
 	for {
 		_, msg, err := ws.ReadMessage()
@@ -34,21 +41,51 @@ func GameGet(c echo.Context) error {
 		}
 		parser := protocol.NewParser(msg)
 		data := parser.Parse()
-		fmt.Printf("received: %+v\n", data)
 
-		buf := handleData(&data, &g)
+		if token, ok := resumeToken(&data); ok {
+			resumed, err := sessions.resume(token, ws)
+			if err != nil {
+				err = s.write([]protocol.Builder{protocol.NewBuilder().AddError(err.Error())})
+				if err != nil {
+					c.Logger().Error(err)
+					break
+				}
+				continue
+			}
+			if resumed != s {
+				s.detach(ws)
+				s = resumed
+			}
+			continue
+		}
+
+		s.mu.Lock()
+		bufs := s.handleData(&data)
+		think := s.computerToMove() && !s.thinking
+		if think {
+			s.thinking = true
+		}
+		s.mu.Unlock()
 
-		err = ws.WriteMessage(websocket.TextMessage, buf)
+		err = s.write(bufs)
 		if err != nil {
 			c.Logger().Error(err)
 			break
 		}
+		if think {
+			go s.think()
+		}
 	}
 	return nil
 }
 
-func handleData(data *types.Data, game *game.Game) protocol.Builder {
+func (s *session) handleData(data *types.Data) []protocol.Builder {
+	g := &s.g
 	b := protocol.NewBuilder()
+	checkResult := false
+	token := ""
+	sendClock := false
+	now := time.Now()
 	switch data.Type {
 	case types.IllegalType:
 		b = b.AddError("invalid message")
@@ -56,39 +93,195 @@ func handleData(data *types.Data, game *game.Game) protocol.Builder {
 	case types.CommandType:
 		cmd := data.Data.(types.Command)
 		fmt.Println("command:", cmd)
-		switch cmd {
-		case "LEGAL_MOVES":
-			legalMoves := game.GetLegalMoves()
-			b = b.AddLegalMoves(legalMoves)
-			break
+		args := strings.Split(string(cmd), string(protocol.SEPARATOR))
+		switch args[0] {
 		case "START":
+			err := s.start(args[1:])
+			if err != nil {
+				b = b.AddError(err.Error())
+				break
+			}
+			if s.token == "" {
+				s.token = sessions.add(s)
+			}
+			token = s.token
 			b = b.AddCommand("OK")
 			break
-		case "ATTACKING_MOVES":
-			attackingMoves := game.GetAttackingMoves()
-			b = b.AddAttackingMoves(attackingMoves)
+		case "TAKEBACK":
+			if s.clock != nil {
+				b = b.AddError("takebacks aren't allowed with a clock")
+				break
+			}
+			err := g.UnmakeMove()
+			if err != nil {
+				b = b.AddError(err.Error())
+				break
+			}
+			// against the computer take back its reply as well
+			if s.computerToMove() && len(g.TrackedMoves()) > 0 {
+				g.UnmakeMove()
+			}
+			s.changed()
+			b = b.AddPosition(g.FEN())
+			break
+		case "CLAIM_DRAW":
+			err := g.ClaimDraw()
+			if err != nil {
+				b = b.AddError(err.Error())
+				break
+			}
+			s.changed()
+			if s.clock != nil {
+				s.clock.Stop(now)
+				s.scheduleFlag()
+				sendClock = true
+			}
+			checkResult = true
+			b = b.AddCommand("OK")
+			break
+		case "CLOCK":
+			if len(args) == 1 {
+				if s.clock == nil {
+					b = b.AddError("no clock is set")
+					break
+				}
+				b = addClock(b, s.clock, now)
+				break
+			}
+			clk, err := parseClock(args[1:], g)
+			if err != nil {
+				b = b.AddError(err.Error())
+				break
+			}
+			s.clock = clk
+			s.scheduleFlag()
+			sendClock = true
+			b = b.AddCommand("OK")
+			break
+		case "ANALYZE":
+			err := s.analyze()
+			if err != nil {
+				b = b.AddError(err.Error())
+				break
+			}
+			b = b.AddCommand("OK")
 			break
 		default:
-			b = b.AddError(fmt.Sprintf("unknown command: %s", cmd))
+			b = handleQuery(args[0], g)
 			break
 		}
 		break
 	case types.MoveType:
 		move := data.Data.(types.Move)
 		fmt.Printf("move: %+v\n", move)
-		game.MakeMove(&move)
+		if s.computerToMove() {
+			b = b.AddError("waiting for the computer to move")
+			break
+		}
+		if s.outOfTime(now) {
+			checkResult = true
+			sendClock = true
+			b = b.AddError("out of time")
+			break
+		}
+		err := g.MakeMove(&move)
+		if err != nil {
+			b = b.AddError(err.Error())
+			break
+		}
+		s.pressClock(now)
+		checkResult = true
+		sendClock = true
 		b = b.AddCommand("OK")
 		break
     case types.PromotionType:
         promotion := data.Data.(types.Promotion)
         fmt.Printf("promotion: %+v\n", promotion)
-        game.MakePromotion(&promotion)
+		if s.computerToMove() {
+			b = b.AddError("waiting for the computer to move")
+			break
+		}
+		if s.outOfTime(now) {
+			checkResult = true
+			sendClock = true
+			b = b.AddError("out of time")
+			break
+		}
+		err := g.MakePromotion(&promotion)
+		if err != nil {
+			b = b.AddError(err.Error())
+			break
+		}
+		s.pressClock(now)
+		checkResult = true
+		sendClock = true
         b = b.AddCommand("OK")
 	case types.PositionType:
 		pos := data.Data.(types.Position)
 		fmt.Println("position:", pos)
+		parsed, err := game.Parse(string(pos))
+		if err != nil {
+			b = b.AddError(err.Error())
+			break
+		}
+		*g = parsed
+		s.changed()
+		if s.clock != nil {
+			// the new position starts with a fresh clock
+			s.clock = clock.New(s.clock.Control(), g.ToMove())
+			s.scheduleFlag()
+			sendClock = true
+		}
 		b = b.AddCommand("OK")
 		break
 	}
+	res := []protocol.Builder{b}
+	if token != "" {
+		res = append(res, protocol.NewBuilder().AddCommand("TOKEN:"+token))
+	}
+	if sendClock && s.clock != nil {
+		res = append(res, addClock(protocol.NewBuilder(), s.clock, now))
+	}
+	if checkResult {
+		status := g.Status()
+		if status.IsOver() {
+			res = append(res, protocol.NewBuilder().AddResult(status))
+		}
+	}
+	return res
+}
+
+// handleQuery answers the commands that only read the game,
+// which every kind of connection supports
+func handleQuery(cmd string, g *game.Game) protocol.Builder {
+	b := protocol.NewBuilder()
+	switch cmd {
+	case "LEGAL_MOVES":
+		legalMoves := g.GetLegalMoves()
+		b = b.AddLegalMoves(legalMoves)
+		break
+	case "POSITION":
+		b = b.AddPosition(g.FEN())
+		break
+	case "PGN":
+		tags := pgn.Tags{{Name: "Date", Value: time.Now().Format("2006.01.02")}}
+		text, err := pgn.String(g, tags)
+		if err != nil {
+			b = b.AddError(err.Error())
+			break
+		}
+		b = b.AddPGN(text)
+		break
+	case "ATTACKING_MOVES":
+		attackingMoves := g.GetAttackingMoves()
+		b = b.AddAttackingMoves(attackingMoves)
+		break
+	case "EVAL":
+		b = b.AddEvaluation(game.Evaluate(g))
+		break
+	default:
+		b = b.AddError(fmt.Sprintf("unknown command: %s", cmd))
+		break
+	}
 	return b
 }
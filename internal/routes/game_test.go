@@ -0,0 +1,392 @@
+package routes
+
+import (
+	"net/http/httptest"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/vincer2040/chess/internal/engine"
+	"github.com/vincer2040/chess/internal/game"
+)
+
+func dial(t *testing.T) *websocket.Conn {
+	return dialGame(t, gameServer(t))
+}
+
+func gameServer(t *testing.T) string {
+	e := echo.New()
+	e.GET("/game", GameGet)
+	server := httptest.NewServer(e)
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http") + "/game"
+}
+
+func dialGame(t *testing.T, url string) *websocket.Conn {
+	ws, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() { ws.Close() })
+	return ws
+}
+
+func send(t *testing.T, ws *websocket.Conn, msg string) {
+	err := ws.WriteMessage(websocket.TextMessage, []byte(msg))
+	if err != nil {
+		t.Fatalf("failed to send %q: %v", msg, err)
+	}
+}
+
+func receive(t *testing.T, ws *websocket.Conn) string {
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, msg, err := ws.ReadMessage()
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	return string(msg)
+}
+
+// start sends a START command and returns the token to resume with
+func start(t *testing.T, ws *websocket.Conn, cmd string) string {
+	send(t, ws, cmd)
+	if msg := receive(t, ws); msg != "#OK\r\n" {
+		t.Fatalf("expected OK, got %q", msg)
+	}
+	msg := receive(t, ws)
+	if !strings.HasPrefix(msg, "#TOKEN:") {
+		t.Fatalf("expected a token, got %q", msg)
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(msg, "#TOKEN:"), "\r\n")
+}
+
+func TestComputerMovesFirstAsWhite(t *testing.T) {
+	ws := dial(t)
+	start(t, ws, "#START:b:1\r\n")
+	if msg := receive(t, ws); msg[0] != '$' {
+		t.Fatalf("expected the computer's move, got %q", msg)
+	}
+	send(t, ws, "#POSITION\r\n")
+	if msg := receive(t, ws); !strings.Contains(msg, " b KQkq ") {
+		t.Errorf("expected black to move, got %q", msg)
+	}
+}
+
+func TestComputerRepliesToMove(t *testing.T) {
+	ws := dial(t)
+	start(t, ws, "#START:w:2\r\n")
+	// e2e4
+	send(t, ws, "$52:36\r\n")
+	if msg := receive(t, ws); msg != "#OK\r\n" {
+		t.Fatalf("expected OK, got %q", msg)
+	}
+	if msg := receive(t, ws); msg[0] != '$' {
+		t.Fatalf("expected the computer's move, got %q", msg)
+	}
+	send(t, ws, "#TAKEBACK\r\n")
+	if msg := receive(t, ws); msg != "+rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1\r\n" {
+		t.Errorf("expected both moves to be taken back, got %q", msg)
+	}
+}
+
+func TestTakeback(t *testing.T) {
+	ws := dial(t)
+	start(t, ws, "#START\r\n")
+	send(t, ws, "#TAKEBACK\r\n")
+	if msg := receive(t, ws); msg != "-no moves to take back\r\n" {
+		t.Errorf("expected an error taking back with no moves, got %q", msg)
+	}
+	for _, move := range []string{"$52:36\r\n", "$11:27\r\n", "$36:27\r\n"} {
+		send(t, ws, move)
+		if msg := receive(t, ws); msg != "#OK\r\n" {
+			t.Fatalf("expected OK, got %q", msg)
+		}
+	}
+	send(t, ws, "#TAKEBACK\r\n")
+	if msg := receive(t, ws); msg != "+rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 2\r\n" {
+		t.Errorf("expected the capture to be taken back, got %q", msg)
+	}
+}
+
+func TestStartRejectsBadArguments(t *testing.T) {
+	ws := dial(t)
+	for _, cmd := range []string{"#START:x\r\n", "#START:w:9\r\n", "#START:w:1:2\r\n"} {
+		send(t, ws, cmd)
+		if msg := receive(t, ws); msg[0] != '-' {
+			t.Errorf("%q: expected an error, got %q", cmd, msg)
+		}
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	ws := dial(t)
+	send(t, ws, "+6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1\r\n")
+	if msg := receive(t, ws); msg != "#OK\r\n" {
+		t.Fatalf("expected OK, got %q", msg)
+	}
+	send(t, ws, "#ANALYZE\r\n")
+	if msg := receive(t, ws); msg != "#OK\r\n" {
+		t.Fatalf("expected OK, got %q", msg)
+	}
+	msg := receive(t, ws)
+	if !strings.HasPrefix(msg, "@") || !strings.Contains(msg, ":mate:1:a1a8") {
+		t.Errorf("expected mate in one with a1a8, got %q", msg)
+	}
+}
+
+// buildStandin builds the stand-in uci engine the driver is tested with
+func buildStandin(t *testing.T) string {
+	standin := filepath.Join(t.TempDir(), "standin")
+	out, err := exec.Command("go", "build", "-o", standin, "../ucidriver/testdata/standin").CombinedOutput()
+	if err != nil {
+		t.Fatalf("failed to build the stand-in engine: %s", out)
+	}
+	return standin
+}
+
+func TestExternalOpponent(t *testing.T) {
+	t.Setenv(externalEngineEnv, buildStandin(t))
+
+	ws := dial(t)
+	start(t, ws, "#START:w:uci\r\n")
+	// e2e4, which the stand-in answers with its first move
+	send(t, ws, "$52:36\r\n")
+	if msg := receive(t, ws); msg != "#OK\r\n" {
+		t.Fatalf("expected OK, got %q", msg)
+	}
+	if msg := receive(t, ws); msg[0] != '$' {
+		t.Fatalf("expected the engine's move, got %q", msg)
+	}
+}
+
+func TestExternalOpponentTimesOut(t *testing.T) {
+	t.Setenv(externalEngineEnv, buildStandin(t))
+	// the stand-in doesn't move until it is told to stop
+	t.Setenv("STANDIN_SLOW", "1")
+	defer func(limits engine.Limits) { externalLimits = limits }(externalLimits)
+	externalLimits = engine.Limits{Time: 10 * time.Millisecond}
+
+	ws := dial(t)
+	start(t, ws, "#START:w:uci\r\n")
+	send(t, ws, "$52:36\r\n")
+	if msg := receive(t, ws); msg != "#OK\r\n" {
+		t.Fatalf("expected OK, got %q", msg)
+	}
+	// the driver gives the engine a few seconds past its time
+	ws.SetReadDeadline(time.Now().Add(15 * time.Second))
+	_, msg, err := ws.ReadMessage()
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	if !strings.HasPrefix(string(msg), "-the engine failed to move") {
+		t.Fatalf("expected the engine's failure, got %q", msg)
+	}
+	// and the built in engine moves in its place
+	if msg := receive(t, ws); msg[0] != '$' {
+		t.Fatalf("expected the built in engine's move, got %q", msg)
+	}
+}
+
+func TestExternalOpponentNotSet(t *testing.T) {
+	t.Setenv(externalEngineEnv, "")
+	ws := dial(t)
+	send(t, ws, "#START:w:uci\r\n")
+	if msg := receive(t, ws); msg[0] != '-' {
+		t.Errorf("expected an error, got %q", msg)
+	}
+}
+
+func TestResume(t *testing.T) {
+	url := gameServer(t)
+	ws := dialGame(t, url)
+	token := start(t, ws, "#START\r\n")
+	for _, move := range []string{"$52:36\r\n", "$12:28\r\n"} {
+		send(t, ws, move)
+		if msg := receive(t, ws); msg != "#OK\r\n" {
+			t.Fatalf("expected OK, got %q", msg)
+		}
+	}
+	ws.Close()
+
+	resumed := dialGame(t, url)
+	send(t, resumed, "#RESUME:"+token+"\r\n")
+	expected := []string{
+		"#OK\r\n",
+		"+rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1\r\n",
+		"$52:36\r\n",
+		"$12:28\r\n",
+		"+rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2\r\n",
+	}
+	for _, want := range expected {
+		if msg := receive(t, resumed); msg != want {
+			t.Fatalf("expected %q, got %q", want, msg)
+		}
+	}
+	// the game carries on where it was
+	send(t, resumed, "$62:45\r\n")
+	if msg := receive(t, resumed); msg != "#OK\r\n" {
+		t.Fatalf("expected OK, got %q", msg)
+	}
+}
+
+func TestResumeTakesOverSocket(t *testing.T) {
+	url := gameServer(t)
+	ws := dialGame(t, url)
+	token := start(t, ws, "#START:w:1\r\n")
+
+	resumed := dialGame(t, url)
+	send(t, resumed, "#RESUME:"+token+"\r\n")
+	if msg := receive(t, resumed); msg != "#OK\r\n" {
+		t.Fatalf("expected OK, got %q", msg)
+	}
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, _, err := ws.ReadMessage(); err == nil {
+		t.Errorf("expected the old socket to be closed")
+	}
+}
+
+func TestResumeAfterGracePeriod(t *testing.T) {
+	t.Setenv(resumeGraceEnv, "10ms")
+	url := gameServer(t)
+	ws := dialGame(t, url)
+	token := start(t, ws, "#START\r\n")
+	ws.Close()
+	time.Sleep(100 * time.Millisecond)
+
+	resumed := dialGame(t, url)
+	send(t, resumed, "#RESUME:"+token+"\r\n")
+	if msg := receive(t, resumed); msg != "-"+errSessionExpired.Error()+"\r\n" {
+		t.Errorf("expected the session to have expired, got %q", msg)
+	}
+	send(t, resumed, "#RESUME:unknown\r\n")
+	if msg := receive(t, resumed); msg[0] != '-' {
+		t.Errorf("expected an error, got %q", msg)
+	}
+}
+
+func TestMoveErrors(t *testing.T) {
+	ws := dial(t)
+	start(t, ws, "#START\r\n")
+	tests := []struct {
+		msg  string
+		want string
+	}{
+		{"$52:28\r\n", "-illegal move e2e5\r\n"},
+		{"$12:28\r\n", "-piece on e7 does not belong to the side to move\r\n"},
+		{"$36:28\r\n", "-no piece on e4\r\n"},
+		{"!52:36:q\r\n", "-move e2e4 is not a promotion\r\n"},
+		{"!52:36:x\r\n", "-invalid message\r\n"},
+	}
+	for _, tt := range tests {
+		send(t, ws, tt.msg)
+		if msg := receive(t, ws); msg != tt.want {
+			t.Errorf("%q: expected %q, got %q", tt.msg, tt.want, msg)
+		}
+	}
+	// none of them changed the game
+	send(t, ws, "#POSITION\r\n")
+	if msg := receive(t, ws); msg != "+"+game.STARTING_POSITION+"\r\n" {
+		t.Errorf("expected the starting position, got %q", msg)
+	}
+	send(t, ws, "$52:36\r\n")
+	if msg := receive(t, ws); msg != "#OK\r\n" {
+		t.Errorf("expected OK, got %q", msg)
+	}
+}
+
+func TestClock(t *testing.T) {
+	ws := dial(t)
+	start(t, ws, "#START\r\n")
+	for _, cmd := range []string{"#CLOCK\r\n", "#CLOCK:x\r\n", "#CLOCK:5+3:sandglass\r\n"} {
+		send(t, ws, cmd)
+		if msg := receive(t, ws); msg[0] != '-' {
+			t.Errorf("%q: expected an error, got %q", cmd, msg)
+		}
+	}
+	send(t, ws, "#CLOCK:5+3\r\n")
+	for _, want := range []string{"#OK\r\n", "|300000:300000:-\r\n"} {
+		if msg := receive(t, ws); msg != want {
+			t.Fatalf("expected %q, got %q", want, msg)
+		}
+	}
+	// the first move is free and starts black's time
+	send(t, ws, "$52:36\r\n")
+	for _, want := range []string{"#OK\r\n", "|300000:300000:b\r\n"} {
+		if msg := receive(t, ws); msg != want {
+			t.Fatalf("expected %q, got %q", want, msg)
+		}
+	}
+	send(t, ws, "#CLOCK\r\n")
+	if msg := receive(t, ws); !strings.HasPrefix(msg, "|300000:") || !strings.HasSuffix(msg, ":b\r\n") {
+		t.Errorf("expected black's time to be running, got %q", msg)
+	}
+	send(t, ws, "#CLOCK:1\r\n")
+	if msg := receive(t, ws); msg[0] != '-' {
+		t.Errorf("expected an error setting the clock mid game, got %q", msg)
+	}
+	send(t, ws, "#TAKEBACK\r\n")
+	if msg := receive(t, ws); msg[0] != '-' {
+		t.Errorf("expected an error taking back with a clock, got %q", msg)
+	}
+}
+
+func TestFlagFall(t *testing.T) {
+	tests := []struct {
+		name     string
+		position string
+		move     string
+		result   string
+	}{
+		{"black flags", "+4k3/8/8/8/8/8/4P3/4K3 w - - 0 1\r\n", "$52:44\r\n", "=1-0:white wins on time\r\n"},
+		{"white can't mate", "+4k3/4p3/8/8/8/8/8/4K3 w - - 0 1\r\n", "$60:59\r\n", "=1/2-1/2:draw by timeout vs insufficient material\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ws := dial(t)
+			start(t, ws, "#START\r\n")
+			send(t, ws, tt.position)
+			if msg := receive(t, ws); msg != "#OK\r\n" {
+				t.Fatalf("expected OK, got %q", msg)
+			}
+			// 0.6 seconds each
+			send(t, ws, "#CLOCK:0.01\r\n")
+			receive(t, ws)
+			receive(t, ws)
+			send(t, ws, tt.move)
+			receive(t, ws)
+			receive(t, ws)
+			// nothing more is sent, the server ends the game when black's time is up
+			for _, want := range []string{"|600:0:-\r\n", tt.result} {
+				if msg := receive(t, ws); msg != want {
+					t.Fatalf("expected %q, got %q", want, msg)
+				}
+			}
+		})
+	}
+}
+
+func TestClaimDraw(t *testing.T) {
+	ws := dial(t)
+	send(t, ws, "#CLAIM_DRAW\r\n")
+	if msg := receive(t, ws); msg != "-no draw to claim\r\n" {
+		t.Errorf("expected an error, got %q", msg)
+	}
+	send(t, ws, "+4k3/8/8/8/8/8/8/R3K3 w - - 100 80\r\n")
+	if msg := receive(t, ws); msg != "#OK\r\n" {
+		t.Fatalf("expected OK, got %q", msg)
+	}
+	send(t, ws, "#CLAIM_DRAW\r\n")
+	for _, want := range []string{"#OK\r\n", "=1/2-1/2:draw by the fifty move rule\r\n"} {
+		if msg := receive(t, ws); msg != want {
+			t.Fatalf("expected %q, got %q", want, msg)
+		}
+	}
+	send(t, ws, "#CLAIM_DRAW\r\n")
+	if msg := receive(t, ws); msg != "-game is over\r\n" {
+		t.Errorf("expected an error, got %q", msg)
+	}
+}
@@ -0,0 +1,104 @@
+package routes
+
+import (
+	"os"
+
+	"github.com/vincer2040/chess/internal/engine"
+	"github.com/vincer2040/chess/internal/game"
+	"github.com/vincer2040/chess/internal/ucidriver"
+)
+
+// set to the path of a uci engine to play and analyse with it
+const externalEngineEnv = "CHESS_UCI_ENGINE"
+
+// opponent searches for the computer's moves and for analysis,
+// with either the built in engine or an external uci engine
+type opponent interface {
+	search(g *game.Game, limits engine.Limits) (analysis, error)
+	close()
+}
+
+// analysis is a search result, scored from white's point of view.
+// mate is the moves to mate when one has been found
+type analysis struct {
+	move  game.Move
+	depth int
+	score int
+	mate  int
+	pv    []string
+}
+
+type builtin struct {
+	engine *engine.Engine
+}
+
+func newBuiltin() *builtin {
+	return &builtin{engine: engine.New(16)}
+}
+
+func (b *builtin) search(g *game.Game, limits engine.Limits) (analysis, error) {
+	res, err := b.engine.Search(g, limits)
+	if err != nil {
+		return analysis{}, err
+	}
+	a := analysis{move: res.Move, depth: res.Depth, score: res.Score}
+	if moves, ok := engine.MateIn(res.Score); ok {
+		a.mate = moves
+		a.score = 0
+	}
+	for _, m := range res.PV {
+		a.pv = append(a.pv, m.String())
+	}
+	return fromWhite(g, a), nil
+}
+
+func (b *builtin) close() {}
+
+type external struct {
+	engine *ucidriver.Engine
+}
+
+// newExternal starts the engine named by CHESS_UCI_ENGINE
+func newExternal() (*external, error) {
+	e, err := ucidriver.Start(os.Getenv(externalEngineEnv))
+	if err != nil {
+		return nil, err
+	}
+	return &external{engine: e}, nil
+}
+
+func (e *external) search(g *game.Game, limits engine.Limits) (analysis, error) {
+	res, err := e.engine.Search(g, limits)
+	if err != nil {
+		return analysis{}, err
+	}
+	a := analysis{
+		move:  res.Move,
+		depth: res.Info.Depth,
+		score: res.Info.Score,
+		mate:  res.Info.Mate,
+		pv:    res.Info.PV,
+	}
+	return fromWhite(g, a), nil
+}
+
+func (e *external) close() {
+	e.engine.Close()
+}
+
+// newAnalyst picks the external engine when there is one
+func newAnalyst() (opponent, error) {
+	if os.Getenv(externalEngineEnv) == "" {
+		return newBuiltin(), nil
+	}
+	return newExternal()
+}
+
+// fromWhite turns a score from the side to move's point of view
+func fromWhite(g *game.Game, a analysis) analysis {
+	if g.ToMove() == 'b' {
+		a.score = -a.score
+		a.mate = -a.mate
+	}
+	return a
+}
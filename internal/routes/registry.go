@@ -0,0 +1,55 @@
+package routes
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+)
+
+// registry holds the multiplayer games by id
+type registry struct {
+	mu    sync.Mutex
+	rooms map[string]*room
+}
+
+var rooms = newRegistry()
+
+func newRegistry() *registry {
+	return &registry{rooms: make(map[string]*room)}
+}
+
+// create starts a new room under an id nobody has guessed
+func (r *registry) create() *room {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for {
+		id := randomID(6)
+		if _, ok := r.rooms[id]; ok {
+			continue
+		}
+		rm := newRoom(id, r)
+		r.rooms[id] = rm
+		go rm.run()
+		return rm
+	}
+}
+
+func (r *registry) get(id string) (*room, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rm, ok := r.rooms[id]
+	return rm, ok
+}
+
+func (r *registry) remove(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.rooms, id)
+}
+
+// randomID is n random bytes in hex
+func randomID(n int) string {
+	buf := make([]byte, n)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,179 @@
+package routes
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/vincer2040/chess/internal/clock"
+	"github.com/vincer2040/chess/internal/game"
+	"github.com/vincer2040/chess/internal/protocol"
+	"github.com/vincer2040/chess/internal/types"
+)
+
+// set to a duration such as 5m to change how long a game
+// waits for its socket to come back
+const resumeGraceEnv = "CHESS_RESUME_GRACE"
+
+const defaultResumeGrace = 2 * time.Minute
+
+var errSessionExpired = errors.New("session has expired")
+
+// sessionStore holds the sessions that can be resumed by token
+type sessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]*session
+}
+
+var sessions = &sessionStore{sessions: make(map[string]*session)}
+
+// add gives s a token it can be resumed with
+func (st *sessionStore) add(s *session) string {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for {
+		token := randomID(16)
+		if _, ok := st.sessions[token]; ok {
+			continue
+		}
+		st.sessions[token] = s
+		return token
+	}
+}
+
+// resume reattaches the session for token to ws
+func (st *sessionStore) resume(token string, ws *websocket.Conn) (*session, error) {
+	st.mu.Lock()
+	s, ok := st.sessions[token]
+	st.mu.Unlock()
+	if !ok {
+		return nil, errSessionExpired
+	}
+	err := s.attach(ws)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// expire closes s if its socket didn't come back in time
+func (st *sessionStore) expire(token string, s *session) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	s.mu.Lock()
+	detached := s.ws == nil
+	s.mu.Unlock()
+	if st.sessions[token] != s || !detached {
+		return
+	}
+	delete(st.sessions, token)
+	s.close()
+}
+
+func resumeGrace(logger echo.Logger) time.Duration {
+	v := os.Getenv(resumeGraceEnv)
+	if v == "" {
+		return defaultResumeGrace
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		logger.Warnf("invalid %s %q, using %v", resumeGraceEnv, v, defaultResumeGrace)
+		return defaultResumeGrace
+	}
+	return d
+}
+
+// resumeToken reports the token of a RESUME command
+func resumeToken(data *types.Data) (string, bool) {
+	if data.Type != types.CommandType {
+		return "", false
+	}
+	args := strings.Split(string(data.Data.(types.Command)), string(protocol.SEPARATOR))
+	if args[0] != "RESUME" {
+		return "", false
+	}
+	return strings.Join(args[1:], string(protocol.SEPARATOR)), true
+}
+
+// detach lets go of ws once it has gone away. a session that was
+// started is kept for the grace period in case the client resumes,
+// anything else is closed straight away
+func (s *session) detach(ws *websocket.Conn) {
+	s.mu.Lock()
+	if s.ws != ws {
+		// another socket has taken over
+		s.mu.Unlock()
+		return
+	}
+	s.writeMu.Lock()
+	s.ws = nil
+	s.writeMu.Unlock()
+	token := s.token
+	if token != "" {
+		s.expiry = time.AfterFunc(resumeGrace(s.logger), func() {
+			sessions.expire(token, s)
+		})
+	}
+	s.mu.Unlock()
+	if token == "" {
+		s.close()
+	}
+}
+
+// attach moves s onto ws and replays the game to it. a socket
+// still attached is closed, since only one can play the game
+func (s *session) attach(ws *websocket.Conn) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return errSessionExpired
+	}
+	if s.expiry != nil {
+		s.expiry.Stop()
+		s.expiry = nil
+	}
+	s.writeMu.Lock()
+	old := s.ws
+	s.ws = ws
+	err := s.writeLocked(s.replay())
+	s.writeMu.Unlock()
+	think := s.computerToMove() && !s.thinking
+	if think {
+		s.thinking = true
+	}
+	s.mu.Unlock()
+
+	if old != nil && old != ws {
+		old.Close()
+	}
+	if think {
+		go s.think()
+	}
+	return err
+}
+
+func (s *session) replay() []protocol.Builder {
+	return append([]protocol.Builder{protocol.NewBuilder().AddCommand("OK")}, replay(&s.g, s.clock)...)
+}
+
+// replay is everything a client needs to pick the game back up:
+// the starting position, each move since, and where that leaves
+// the game and its clock
+func replay(g *game.Game, clk *clock.Clock) []protocol.Builder {
+	res := []protocol.Builder{protocol.NewBuilder().AddPosition(g.StartingFEN())}
+	for _, m := range g.TrackedMoves() {
+		res = append(res, addPlayed(protocol.NewBuilder(), m.Data()))
+	}
+	res = append(res, protocol.NewBuilder().AddPosition(g.FEN()))
+	if clk != nil {
+		res = append(res, addClock(protocol.NewBuilder(), clk, time.Now()))
+	}
+	if status := g.Status(); status.IsOver() {
+		res = append(res, protocol.NewBuilder().AddResult(status))
+	}
+	return res
+}
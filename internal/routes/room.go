@@ -0,0 +1,534 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/vincer2040/chess/internal/clock"
+	"github.com/vincer2040/chess/internal/game"
+	"github.com/vincer2040/chess/internal/protocol"
+	"github.com/vincer2040/chess/internal/types"
+)
+
+const (
+	// a room nobody is in is closed after this long
+	roomIdleTimeout = 10 * time.Minute
+	// messages a player can fall behind by before being dropped
+	playerBuffer = 64
+)
+
+// room is a game between two sockets, watched by any number of
+// spectators. the game belongs to the run goroutine and everything
+// else talks to it over channels
+type room struct {
+	id       string
+	registry *registry
+	joins    chan *player
+	leaves   chan *player
+	requests chan request
+	// seats whose player didn't come back in time
+	expired chan *seat
+	// closed once run has returned
+	done chan struct{}
+
+	// only touched by run
+	g       game.Game
+	seats   map[byte]*seat
+	players map[*player]bool
+	// nil unless a player set a time control, with flag
+	// set for when the side to move runs out of time
+	clock *clock.Clock
+	flag  *time.Timer
+}
+
+// player is one socket in a room. the room writes to send and
+// a goroutine per socket passes it on, so a slow socket can't
+// hold up the room
+type player struct {
+	color byte
+	// spectators only watch and never get a seat
+	spectator bool
+	// the token of the seat the player had before reconnecting
+	token  string
+	send   chan protocol.Builder
+	logger echo.Logger
+}
+
+// seat is held for its player while their socket is gone,
+// so they can take it back with its token
+type seat struct {
+	token string
+	// nil while the seat is held
+	player *player
+	// gives the seat up once the grace period is over
+	expiry *time.Timer
+}
+
+type request struct {
+	from *player
+	data types.Data
+}
+
+func newRoom(id string, r *registry) *room {
+	return &room{
+		id:       id,
+		registry: r,
+		joins:    make(chan *player),
+		leaves:   make(chan *player),
+		requests: make(chan request),
+		expired:  make(chan *seat),
+		done:     make(chan struct{}),
+		g:        game.New(game.STARTING_POSITION),
+		seats:    make(map[byte]*seat),
+		players:  make(map[*player]bool),
+	}
+}
+
+func newPlayer(spectator bool, token string, logger echo.Logger) *player {
+	return &player{
+		spectator: spectator,
+		token:     token,
+		send:      make(chan protocol.Builder, playerBuffer),
+		logger:    logger,
+	}
+}
+
+func (rm *room) run() {
+	defer close(rm.done)
+	defer rm.registry.remove(rm.id)
+	for {
+		var idle <-chan time.Time
+		if len(rm.players) == 0 {
+			idle = time.After(roomIdleTimeout)
+		}
+		var flag <-chan time.Time
+		if rm.flag != nil {
+			flag = rm.flag.C
+		}
+		select {
+		case p := <-rm.joins:
+			rm.add(p)
+			break
+		case p := <-rm.leaves:
+			if !rm.players[p] {
+				break
+			}
+			rm.drop(p)
+			if !p.spectator {
+				rm.broadcast(nil, protocol.NewBuilder().AddCommand("LEFT:"+string(p.color)))
+			}
+			break
+		case req := <-rm.requests:
+			if !rm.players[req.from] {
+				break
+			}
+			rm.handle(req)
+			break
+		case st := <-rm.expired:
+			for color, held := range rm.seats {
+				if held == st && st.player == nil {
+					delete(rm.seats, color)
+				}
+			}
+			break
+		case <-flag:
+			rm.flag = nil
+			rm.timeUp(time.Now())
+			break
+		case <-idle:
+			return
+		}
+	}
+}
+
+// add seats p in the first free seat, white first, unless it
+// has the token of a seat to take back. spectators are caught
+// up on the game so far instead
+func (rm *room) add(p *player) {
+	rm.players[p] = true
+	if p.spectator {
+		rm.deliver(p, protocol.NewBuilder().AddCommand("WATCHING"))
+		rm.deliver(p, protocol.NewBuilder().AddPosition(rm.g.FEN()))
+		if rm.clock != nil {
+			rm.deliver(p, addClock(protocol.NewBuilder(), rm.clock, time.Now()))
+		}
+		if status := rm.g.Status(); status.IsOver() {
+			rm.deliver(p, protocol.NewBuilder().AddResult(status))
+		}
+		return
+	}
+	if rm.takeBack(p, p.token) {
+		return
+	}
+	for _, color := range []byte{'w', 'b'} {
+		if rm.seats[color] == nil {
+			rm.seats[color] = &seat{token: randomID(16), player: p}
+			p.color = color
+			break
+		}
+	}
+	if p.color == 0 {
+		// p stays to watch, since it may be a player come back
+		// to take its seat with RESUME
+		p.spectator = true
+		rm.deliver(p, protocol.NewBuilder().AddError("game is full"))
+		return
+	}
+	rm.deliver(p, protocol.NewBuilder().AddCommand("SEAT:"+string(p.color)))
+	rm.deliver(p, protocol.NewBuilder().AddCommand("TOKEN:"+rm.seats[p.color].token))
+	rm.catchUp(p)
+	rm.broadcast(p, protocol.NewBuilder().AddCommand("JOINED:"+string(p.color)))
+}
+
+// takeBack gives p the seat token is for, reporting false if no
+// seat has it. a socket still in the seat is let go, since only
+// one can play for a side, and p is sent the game so far
+func (rm *room) takeBack(p *player, token string) bool {
+	if token == "" {
+		return false
+	}
+	for color, st := range rm.seats {
+		if st.token != token {
+			continue
+		}
+		if st.expiry != nil {
+			st.expiry.Stop()
+			st.expiry = nil
+		}
+		if !p.spectator && p.color != 0 && p.color != color {
+			// give up the seat p was handed on joining
+			delete(rm.seats, p.color)
+			rm.broadcast(p, protocol.NewBuilder().AddCommand("LEFT:"+string(p.color)))
+		}
+		old := st.player
+		st.player = p
+		p.color = color
+		p.spectator = false
+		if old != nil && old != p {
+			rm.drop(old)
+		}
+		rm.deliver(p, protocol.NewBuilder().AddCommand("SEAT:"+string(color)))
+		for _, b := range replay(&rm.g, rm.clock) {
+			rm.deliver(p, b)
+		}
+		rm.broadcast(p, protocol.NewBuilder().AddCommand("JOINED:"+string(color)))
+		return true
+	}
+	return false
+}
+
+// catchUp sends a newly seated player where the game and its clock are
+func (rm *room) catchUp(p *player) {
+	rm.deliver(p, protocol.NewBuilder().AddPosition(rm.g.FEN()))
+	if rm.clock != nil {
+		rm.deliver(p, addClock(protocol.NewBuilder(), rm.clock, time.Now()))
+	}
+	if status := rm.g.Status(); status.IsOver() {
+		rm.deliver(p, protocol.NewBuilder().AddResult(status))
+	}
+}
+
+// drop removes p from the room and lets its socket go. the seat
+// p had is held for the grace period in case it comes back
+func (rm *room) drop(p *player) {
+	if !rm.players[p] {
+		return
+	}
+	delete(rm.players, p)
+	if st := rm.seats[p.color]; st != nil && st.player == p {
+		st.player = nil
+		st.expiry = time.AfterFunc(resumeGrace(p.logger), func() {
+			select {
+			case rm.expired <- st:
+				break
+			case <-rm.done:
+				break
+			}
+		})
+	}
+	close(p.send)
+}
+
+// deliver queues a message for p, dropping p if it has fallen too far behind
+func (rm *room) deliver(p *player, b protocol.Builder) {
+	if !rm.players[p] {
+		return
+	}
+	select {
+	case p.send <- b:
+		break
+	default:
+		p.logger.Warnf("dropping slow player in room %s", rm.id)
+		rm.drop(p)
+		break
+	}
+}
+
+// broadcast sends b to everyone in the room apart from except
+func (rm *room) broadcast(except *player, b protocol.Builder) {
+	for p := range rm.players {
+		if p != except {
+			rm.deliver(p, b)
+		}
+	}
+}
+
+func (rm *room) handle(req request) {
+	g := &rm.g
+	p := req.from
+	reply := func(b protocol.Builder) {
+		rm.deliver(p, b)
+	}
+	played := protocol.NewBuilder()
+	checkResult := false
+	now := time.Now()
+	switch req.data.Type {
+	case types.IllegalType:
+		reply(protocol.NewBuilder().AddError("invalid message"))
+		return
+	case types.PositionType:
+		reply(protocol.NewBuilder().AddError("the position can't be set in a multiplayer game"))
+		return
+	case types.CommandType:
+		cmd := string(req.data.Data.(types.Command))
+		args := strings.Split(cmd, string(protocol.SEPARATOR))
+		switch args[0] {
+		case "RESUME":
+			token := strings.Join(args[1:], string(protocol.SEPARATOR))
+			if !rm.takeBack(p, token) {
+				reply(protocol.NewBuilder().AddError(errSessionExpired.Error()))
+			}
+			return
+		case "START", "TAKEBACK", "ANALYZE":
+			reply(protocol.NewBuilder().AddError(fmt.Sprintf("%s is not supported in a multiplayer game", args[0])))
+			return
+		case "CLAIM_DRAW":
+			if p.spectator {
+				reply(protocol.NewBuilder().AddError("spectators can't claim a draw"))
+				return
+			}
+			if g.ToMove() != p.color {
+				reply(protocol.NewBuilder().AddError("a draw can only be claimed on your turn"))
+				return
+			}
+			err := g.ClaimDraw()
+			if err != nil {
+				reply(protocol.NewBuilder().AddError(err.Error()))
+				return
+			}
+			reply(protocol.NewBuilder().AddCommand("OK"))
+			if rm.clock != nil {
+				rm.clock.Stop(now)
+				rm.scheduleFlag()
+				rm.broadcast(nil, addClock(protocol.NewBuilder(), rm.clock, now))
+			}
+			checkResult = true
+			break
+		case "CLOCK":
+			if len(args) == 1 {
+				if rm.clock == nil {
+					reply(protocol.NewBuilder().AddError("no clock is set"))
+					return
+				}
+				reply(addClock(protocol.NewBuilder(), rm.clock, now))
+				return
+			}
+			if p.spectator {
+				reply(protocol.NewBuilder().AddError("spectators can't set the clock"))
+				return
+			}
+			clk, err := parseClock(args[1:], g)
+			if err != nil {
+				reply(protocol.NewBuilder().AddError(err.Error()))
+				return
+			}
+			rm.clock = clk
+			rm.scheduleFlag()
+			reply(protocol.NewBuilder().AddCommand("OK"))
+			rm.broadcast(nil, addClock(protocol.NewBuilder(), rm.clock, now))
+			return
+		default:
+			reply(handleQuery(args[0], g))
+			return
+		}
+		break
+	case types.MoveType:
+		move := req.data.Data.(types.Move)
+		if p.spectator {
+			reply(protocol.NewBuilder().AddError("spectators can't move"))
+			return
+		}
+		if g.ToMove() != p.color {
+			reply(protocol.NewBuilder().AddError("not your turn"))
+			return
+		}
+		if rm.outOfTime(now) {
+			reply(protocol.NewBuilder().AddError("out of time"))
+			return
+		}
+		err := g.MakeMove(&move)
+		if err != nil {
+			reply(protocol.NewBuilder().AddError(err.Error()))
+			return
+		}
+		pressClock(g, rm.clock, now)
+		rm.scheduleFlag()
+		reply(protocol.NewBuilder().AddCommand("OK"))
+		played = played.AddMove(&move)
+		break
+	case types.PromotionType:
+		promotion := req.data.Data.(types.Promotion)
+		if p.spectator {
+			reply(protocol.NewBuilder().AddError("spectators can't move"))
+			return
+		}
+		if g.ToMove() != p.color {
+			reply(protocol.NewBuilder().AddError("not your turn"))
+			return
+		}
+		if rm.outOfTime(now) {
+			reply(protocol.NewBuilder().AddError("out of time"))
+			return
+		}
+		err := g.MakePromotion(&promotion)
+		if err != nil {
+			reply(protocol.NewBuilder().AddError(err.Error()))
+			return
+		}
+		pressClock(g, rm.clock, now)
+		rm.scheduleFlag()
+		reply(protocol.NewBuilder().AddCommand("OK"))
+		played = played.AddPromotion(&promotion)
+		break
+	}
+	if len(played) != 0 {
+		rm.broadcast(p, played)
+		if rm.clock != nil {
+			rm.broadcast(nil, addClock(protocol.NewBuilder(), rm.clock, now))
+		}
+		checkResult = true
+	}
+	if !checkResult {
+		return
+	}
+	if status := g.Status(); status.IsOver() {
+		rm.broadcast(nil, protocol.NewBuilder().AddResult(status))
+	}
+}
+
+// outOfTime ends the game if the side to move has run out of
+// time, letting everyone know
+func (rm *room) outOfTime(now time.Time) bool {
+	if !flagFall(&rm.g, rm.clock, now) {
+		return false
+	}
+	rm.scheduleFlag()
+	rm.broadcast(nil, addClock(protocol.NewBuilder(), rm.clock, now))
+	rm.broadcast(nil, protocol.NewBuilder().AddResult(rm.g.Status()))
+	return true
+}
+
+// timeUp is run once flag fires. if the side to move
+// moved just in time the flag is set again
+func (rm *room) timeUp(now time.Time) {
+	if !rm.outOfTime(now) {
+		rm.scheduleFlag()
+	}
+}
+
+// scheduleFlag sets flag for when the side to move runs out of time
+func (rm *room) scheduleFlag() {
+	if rm.flag != nil {
+		rm.flag.Stop()
+		rm.flag = nil
+	}
+	if rm.clock == nil || !rm.clock.Running() || rm.g.Status().IsOver() {
+		return
+	}
+	rm.flag = time.NewTimer(time.Until(rm.clock.Deadline()))
+}
+
+// join adds p to the room, reporting false if the room has closed
+func (rm *room) join(p *player) bool {
+	select {
+	case rm.joins <- p:
+		return true
+	case <-rm.done:
+		return false
+	}
+}
+
+func (rm *room) leave(p *player) {
+	select {
+	case rm.leaves <- p:
+		break
+	case <-rm.done:
+		break
+	}
+}
+
+func (rm *room) send(req request) {
+	select {
+	case rm.requests <- req:
+		break
+	case <-rm.done:
+		break
+	}
+}
+
+// GameRoomGet connects a socket to a seat in the multiplayer game in the url.
+// a ?token= from an earlier SEAT takes that seat back if it is still held
+func GameRoomGet(c echo.Context) error {
+	return connectRoom(c, false)
+}
+
+// GameRoomWatch connects a read only socket to the multiplayer game in the url
+func GameRoomWatch(c echo.Context) error {
+	return connectRoom(c, true)
+}
+
+func connectRoom(c echo.Context, spectator bool) error {
+	rm, ok := rooms.get(c.Param("id"))
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotFound, "game not found")
+	}
+	ws, err := upgrader.Upgrade(c.Response(), c.Request(), nil)
+	if err != nil {
+		return err
+	}
+	defer ws.Close()
+
+	p := newPlayer(spectator, c.QueryParam("token"), c.Logger())
+	if !rm.join(p) {
+		return nil
+	}
+	defer rm.leave(p)
+
+	go func() {
+		for buf := range p.send {
+			err := ws.WriteMessage(websocket.TextMessage, buf)
+			if err != nil {
+				c.Logger().Error(err)
+				ws.Close()
+				return
+			}
+		}
+		// the room is done with us
+		ws.Close()
+	}()
+
+	for {
+		_, msg, err := ws.ReadMessage()
+		if err != nil {
+			break
+		}
+		parser := protocol.NewParser(msg)
+		data := parser.Parse()
+		rm.send(request{from: p, data: data})
+	}
+	return nil
+}
@@ -0,0 +1,314 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+)
+
+func roomServer(t *testing.T) string {
+	e := echo.New()
+	e.GET("/game/:id", GameRoomGet)
+	e.GET("/game/:id/watch", GameRoomWatch)
+	server := httptest.NewServer(e)
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func joinRoom(t *testing.T, url, id string) *websocket.Conn {
+	return dialRoom(t, url+"/game/"+id)
+}
+
+func watchRoom(t *testing.T, url, id string) *websocket.Conn {
+	return dialRoom(t, url+"/game/"+id+"/watch")
+}
+
+func dialRoom(t *testing.T, url string) *websocket.Conn {
+	ws, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() { ws.Close() })
+	return ws
+}
+
+func expect(t *testing.T, ws *websocket.Conn, want string) {
+	t.Helper()
+	if msg := receive(t, ws); msg != want {
+		t.Fatalf("expected %q, got %q", want, msg)
+	}
+}
+
+// takeSeat expects the seat a player was given and returns
+// the token to take it back with
+func takeSeat(t *testing.T, ws *websocket.Conn, color string) string {
+	t.Helper()
+	expect(t, ws, "#SEAT:"+color+"\r\n")
+	msg := receive(t, ws)
+	if !strings.HasPrefix(msg, "#TOKEN:") {
+		t.Fatalf("expected a token, got %q", msg)
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(msg, "#TOKEN:"), "\r\n")
+}
+
+func TestRoomPlay(t *testing.T) {
+	url := roomServer(t)
+	id := rooms.create().id
+
+	white := joinRoom(t, url, id)
+	takeSeat(t, white, "w")
+	expect(t, white, "+rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1\r\n")
+	black := joinRoom(t, url, id)
+	takeSeat(t, black, "b")
+	expect(t, black, "+rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1\r\n")
+	expect(t, white, "#JOINED:b\r\n")
+
+	// black can't move for white
+	send(t, black, "$52:36\r\n")
+	expect(t, black, "-not your turn\r\n")
+
+	// 1. f3 e5 2. g4 Qh4#
+	moves := []struct {
+		ws   *websocket.Conn
+		move string
+	}{
+		{white, "$53:45\r\n"},
+		{black, "$12:28\r\n"},
+		{white, "$54:38\r\n"},
+		{black, "$3:39\r\n"},
+	}
+	for i, m := range moves {
+		opponent := black
+		if m.ws == black {
+			opponent = white
+		}
+		send(t, m.ws, m.move)
+		expect(t, m.ws, "#OK\r\n")
+		expect(t, opponent, m.move)
+		if i == len(moves)-1 {
+			expect(t, m.ws, "=0-1:black wins by checkmate\r\n")
+			expect(t, opponent, "=0-1:black wins by checkmate\r\n")
+		}
+	}
+}
+
+func TestRoomFull(t *testing.T) {
+	url := roomServer(t)
+	id := rooms.create().id
+	takeSeat(t, joinRoom(t, url, id), "w")
+	takeSeat(t, joinRoom(t, url, id), "b")
+	expect(t, joinRoom(t, url, id), "-game is full\r\n")
+}
+
+func TestRoomSeatHeldOnLeave(t *testing.T) {
+	url := roomServer(t)
+	id := rooms.create().id
+	white := joinRoom(t, url, id)
+	takeSeat(t, white, "w")
+	expect(t, white, "+rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1\r\n")
+	black := joinRoom(t, url, id)
+	token := takeSeat(t, black, "b")
+	expect(t, white, "#JOINED:b\r\n")
+	send(t, white, "$52:36\r\n")
+	expect(t, white, "#OK\r\n")
+	black.Close()
+	expect(t, white, "#LEFT:b\r\n")
+
+	// nobody else can take the seat while it is held
+	expect(t, joinRoom(t, url, id), "-game is full\r\n")
+	expect(t, joinRoom(t, url, id+"?token=wrong"), "-game is full\r\n")
+
+	// the game is replayed from the start
+	resumed := joinRoom(t, url, id+"?token="+token)
+	expect(t, resumed, "#SEAT:b\r\n")
+	expect(t, resumed, "+rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1\r\n")
+	expect(t, resumed, "$52:36\r\n")
+	expect(t, resumed, "+rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1\r\n")
+	expect(t, white, "#JOINED:b\r\n")
+	send(t, resumed, "$12:28\r\n")
+	expect(t, resumed, "#OK\r\n")
+	expect(t, white, "$12:28\r\n")
+}
+
+func TestRoomResume(t *testing.T) {
+	url := roomServer(t)
+	id := rooms.create().id
+	white := joinRoom(t, url, id)
+	takeSeat(t, white, "w")
+	expect(t, white, "+rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1\r\n")
+	black := joinRoom(t, url, id)
+	token := takeSeat(t, black, "b")
+	expect(t, white, "#JOINED:b\r\n")
+	send(t, white, "$52:36\r\n")
+	expect(t, white, "#OK\r\n")
+	black.Close()
+	expect(t, white, "#LEFT:b\r\n")
+
+	// a socket turned away from a full game can still take its seat back
+	resumed := joinRoom(t, url, id)
+	expect(t, resumed, "-game is full\r\n")
+	send(t, resumed, "#RESUME:wrong\r\n")
+	expect(t, resumed, "-"+errSessionExpired.Error()+"\r\n")
+	send(t, resumed, "#RESUME:"+token+"\r\n")
+	expect(t, resumed, "#SEAT:b\r\n")
+	expect(t, resumed, "+rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1\r\n")
+	expect(t, resumed, "$52:36\r\n")
+	expect(t, resumed, "+rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1\r\n")
+	expect(t, white, "#JOINED:b\r\n")
+	send(t, resumed, "$12:28\r\n")
+	expect(t, resumed, "#OK\r\n")
+	expect(t, white, "$12:28\r\n")
+}
+
+func TestRoomSeatTakenOver(t *testing.T) {
+	url := roomServer(t)
+	id := rooms.create().id
+	white := joinRoom(t, url, id)
+	token := takeSeat(t, white, "w")
+	expect(t, white, "+rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1\r\n")
+
+	resumed := joinRoom(t, url, id+"?token="+token)
+	expect(t, resumed, "#SEAT:w\r\n")
+	white.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, _, err := white.ReadMessage(); err == nil {
+		t.Errorf("expected the old socket to be closed")
+	}
+}
+
+func TestRoomSeatFreedAfterGracePeriod(t *testing.T) {
+	t.Setenv(resumeGraceEnv, "10ms")
+	url := roomServer(t)
+	id := rooms.create().id
+	white := joinRoom(t, url, id)
+	takeSeat(t, white, "w")
+	expect(t, white, "+rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1\r\n")
+	black := joinRoom(t, url, id)
+	token := takeSeat(t, black, "b")
+	expect(t, white, "#JOINED:b\r\n")
+	black.Close()
+	expect(t, white, "#LEFT:b\r\n")
+	time.Sleep(100 * time.Millisecond)
+
+	// the old token is no good and the seat goes to whoever comes next
+	late := joinRoom(t, url, id+"?token="+token)
+	if msg := receive(t, late); msg != "#SEAT:b\r\n" {
+		t.Fatalf("expected the free seat, got %q", msg)
+	}
+	if msg := receive(t, late); msg == "#TOKEN:"+token+"\r\n" {
+		t.Errorf("expected a new token")
+	}
+}
+
+func TestRoomSpectators(t *testing.T) {
+	url := roomServer(t)
+	id := rooms.create().id
+	white := joinRoom(t, url, id)
+	takeSeat(t, white, "w")
+	expect(t, white, "+rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1\r\n")
+	black := joinRoom(t, url, id)
+	takeSeat(t, black, "b")
+	expect(t, black, "+rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1\r\n")
+	expect(t, white, "#JOINED:b\r\n")
+
+	send(t, white, "$53:45\r\n")
+	expect(t, white, "#OK\r\n")
+	expect(t, black, "$53:45\r\n")
+
+	// a spectator joining late is given the position so far
+	spectators := []*websocket.Conn{watchRoom(t, url, id), watchRoom(t, url, id)}
+	for _, ws := range spectators {
+		expect(t, ws, "#WATCHING\r\n")
+		expect(t, ws, "+rnbqkbnr/pppppppp/8/8/8/5P2/PPPPP1PP/RNBQKBNR b KQkq - 0 1\r\n")
+	}
+
+	send(t, spectators[0], "$12:28\r\n")
+	expect(t, spectators[0], "-spectators can't move\r\n")
+	send(t, spectators[0], "#CLAIM_DRAW\r\n")
+	expect(t, spectators[0], "-spectators can't claim a draw\r\n")
+
+	for _, m := range []struct {
+		ws   *websocket.Conn
+		move string
+	}{
+		{black, "$12:28\r\n"},
+		{white, "$54:38\r\n"},
+		{black, "$3:39\r\n"},
+	} {
+		opponent := black
+		if m.ws == black {
+			opponent = white
+		}
+		send(t, m.ws, m.move)
+		expect(t, m.ws, "#OK\r\n")
+		expect(t, opponent, m.move)
+		for _, ws := range spectators {
+			expect(t, ws, m.move)
+		}
+	}
+	for _, ws := range spectators {
+		expect(t, ws, "=0-1:black wins by checkmate\r\n")
+	}
+
+	// spectators don't take a seat, and leaving isn't announced
+	spectators[1].Close()
+	expect(t, joinRoom(t, url, id), "-game is full\r\n")
+	late := watchRoom(t, url, id)
+	expect(t, late, "#WATCHING\r\n")
+	expect(t, late, "+rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3\r\n")
+	expect(t, late, "=0-1:black wins by checkmate\r\n")
+}
+
+func TestRoomNotFound(t *testing.T) {
+	url := roomServer(t)
+	_, resp, err := websocket.DefaultDialer.Dial(url+"/game/missing", nil)
+	if err == nil {
+		t.Fatalf("expected joining a missing game to fail")
+	}
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestRoomClock(t *testing.T) {
+	url := roomServer(t)
+	id := rooms.create().id
+	white := joinRoom(t, url, id)
+	takeSeat(t, white, "w")
+	expect(t, white, "+rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1\r\n")
+	black := joinRoom(t, url, id)
+	takeSeat(t, black, "b")
+	expect(t, black, "+rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1\r\n")
+	expect(t, white, "#JOINED:b\r\n")
+
+	// 0.6 seconds each
+	send(t, black, "#CLOCK:0.01\r\n")
+	expect(t, black, "#OK\r\n")
+	for _, ws := range []*websocket.Conn{white, black} {
+		expect(t, ws, "|600:600:-\r\n")
+	}
+	spectator := watchRoom(t, url, id)
+	expect(t, spectator, "#WATCHING\r\n")
+	expect(t, spectator, "+rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1\r\n")
+	expect(t, spectator, "|600:600:-\r\n")
+
+	send(t, white, "$52:36\r\n")
+	expect(t, white, "#OK\r\n")
+	expect(t, white, "|600:600:b\r\n")
+	expect(t, black, "$52:36\r\n")
+	expect(t, black, "|600:600:b\r\n")
+	expect(t, spectator, "$52:36\r\n")
+	expect(t, spectator, "|600:600:b\r\n")
+
+	// black never moves
+	for _, ws := range []*websocket.Conn{white, black, spectator} {
+		expect(t, ws, "|600:0:-\r\n")
+		expect(t, ws, "=1-0:white wins on time\r\n")
+	}
+	send(t, black, "$12:28\r\n")
+	expect(t, black, "-game is over\r\n")
+}
@@ -6,6 +6,28 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
+type rootData struct {
+	GameID string
+	Watch  bool
+}
+
+// RootGet serves the board. a ?game=<id> link joins that game and
+// adding &watch=1 watches it instead, otherwise the board plays
+// over /game like it always has
 func RootGet(c echo.Context) error {
-	return c.Render(http.StatusOK, "index.html", nil)
+	id := c.QueryParam("game")
+	if id == "" {
+		return c.Render(http.StatusOK, "index.html", rootData{})
+	}
+	if _, ok := rooms.get(id); !ok {
+		return echo.NewHTTPError(http.StatusNotFound, "game not found")
+	}
+	return c.Render(http.StatusOK, "index.html", rootData{GameID: id, Watch: c.QueryParam("watch") != ""})
+}
+
+// RootNew makes a new multiplayer game and sends the client
+// to its link, which can then be shared
+func RootNew(c echo.Context) error {
+	id := rooms.create().id
+	return c.Redirect(http.StatusSeeOther, "/?game="+id)
 }
@@ -0,0 +1,84 @@
+package routes
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+// dataRenderer keeps what the page was rendered with
+type dataRenderer struct {
+	data interface{}
+}
+
+func (r *dataRenderer) Render(w io.Writer, name string, data interface{}, c echo.Context) error {
+	r.data = data
+	return nil
+}
+
+func rootServer() (*echo.Echo, *dataRenderer) {
+	e := echo.New()
+	r := &dataRenderer{}
+	e.Renderer = r
+	e.GET("/", RootGet)
+	e.POST("/new", RootNew)
+	return e, r
+}
+
+func roomCount() int {
+	rooms.mu.Lock()
+	defer rooms.mu.Unlock()
+	return len(rooms.rooms)
+}
+
+func TestRootGetMakesNoRoom(t *testing.T) {
+	e, r := rootServer()
+	before := roomCount()
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if data := r.data.(rootData); data.GameID != "" {
+		t.Errorf("expected the single player board, got game %q", data.GameID)
+	}
+	if after := roomCount(); after != before {
+		t.Errorf("expected no room to be made, had %d now %d", before, after)
+	}
+}
+
+func TestRootNew(t *testing.T) {
+	e, r := rootServer()
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/new", nil))
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("expected 303, got %d", rec.Code)
+	}
+	location := rec.Header().Get("Location")
+	id, ok := strings.CutPrefix(location, "/?game=")
+	if !ok {
+		t.Fatalf("unexpected redirect to %q", location)
+	}
+	if _, ok := rooms.get(id); !ok {
+		t.Fatalf("room %q wasn't made", id)
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, location, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if data := r.data.(rootData); data.GameID != id {
+		t.Errorf("expected game %q, got %q", id, data.GameID)
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?game=missing", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing game, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,411 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/vincer2040/chess/internal/clock"
+	"github.com/vincer2040/chess/internal/engine"
+	"github.com/vincer2040/chess/internal/game"
+	"github.com/vincer2040/chess/internal/protocol"
+	"github.com/vincer2040/chess/internal/types"
+)
+
+// search limits for each difficulty, easiest first
+var difficulties = []engine.Limits{
+	{Depth: 1},
+	{Depth: 2},
+	{Depth: 4, Time: 500 * time.Millisecond},
+	{Depth: 6, Time: time.Second},
+	{Time: 3 * time.Second},
+}
+
+const defaultDifficulty = 3
+
+// how long the external engine gets per move
+// and how long analysis runs for
+var (
+	externalLimits = engine.Limits{Time: time.Second}
+	analysisLimits = engine.Limits{Time: time.Second}
+)
+
+// session is the game played over one socket. the computer
+// thinks on its own goroutine, so anything touching the game
+// has to hold mu, and writes to the socket hold writeMu.
+// changing ws takes both
+type session struct {
+	mu       sync.Mutex
+	g        game.Game
+	computer *computer
+	thinking bool
+	// closed to cut short the computer's search
+	stopSearch chan struct{}
+	// bumped whenever the game is changed by the client so
+	// a search that finishes afterwards knows it is stale
+	version int
+
+	analyst   opponent
+	analyzing bool
+
+	// nil unless the client set a time control
+	clock     *clock.Clock
+	flagTimer *time.Timer
+
+	// set once the game is started so the client can resume it
+	// after losing its socket, which leaves ws nil until then
+	token  string
+	expiry *time.Timer
+	closed bool
+
+	writeMu sync.Mutex
+	ws      *websocket.Conn
+
+	logger echo.Logger
+}
+
+type computer struct {
+	color  byte
+	player opponent
+	limits engine.Limits
+}
+
+func newSession(ws *websocket.Conn, logger echo.Logger) *session {
+	return &session{
+		g:      game.New(game.STARTING_POSITION),
+		ws:     ws,
+		logger: logger,
+	}
+}
+
+// start handles the arguments of START. with none both sides are
+// played from the socket, otherwise the client gives the color it
+// plays and optionally a difficulty from 1 to 5 for the computer,
+// or uci to play the engine set in CHESS_UCI_ENGINE
+func (s *session) start(args []string) error {
+	if len(args) == 0 {
+		s.setComputer(nil)
+		return nil
+	}
+	if len(args) > 2 {
+		return errors.New("usage: START:<w|b>:<difficulty|uci>")
+	}
+	var color byte
+	switch args[0] {
+	case "w":
+		color = 'b'
+		break
+	case "b":
+		color = 'w'
+		break
+	default:
+		return fmt.Errorf("unknown color: %s", args[0])
+	}
+	if len(args) == 2 && args[1] == "uci" {
+		if os.Getenv(externalEngineEnv) == "" {
+			return fmt.Errorf("no uci engine set in %s", externalEngineEnv)
+		}
+		player, err := newExternal()
+		if err != nil {
+			return err
+		}
+		s.setComputer(&computer{color: color, player: player, limits: externalLimits})
+		return nil
+	}
+	difficulty := defaultDifficulty
+	if len(args) == 2 {
+		d, err := strconv.Atoi(args[1])
+		if err != nil || d < 1 || d > len(difficulties) {
+			return fmt.Errorf("difficulty must be from 1 to %d", len(difficulties))
+		}
+		difficulty = d
+	}
+	s.setComputer(&computer{
+		color:  color,
+		player: newBuiltin(),
+		limits: difficulties[difficulty-1],
+	})
+	return nil
+}
+
+// setComputer replaces the computer, letting the old one
+// finish its search before it is shut down
+func (s *session) setComputer(c *computer) {
+	s.changed()
+	if s.computer != nil {
+		go s.computer.player.close()
+	}
+	s.computer = c
+}
+
+// analyze searches the current position and sends the result
+// once it is done, without holding up the socket meanwhile
+func (s *session) analyze() error {
+	if s.analyzing {
+		return errors.New("already analyzing")
+	}
+	if s.g.Status().IsOver() {
+		return errors.New("game is over")
+	}
+	if s.analyst == nil {
+		analyst, err := newAnalyst()
+		if err != nil {
+			return err
+		}
+		s.analyst = analyst
+	}
+	s.analyzing = true
+	pos := s.g.Clone()
+	analyst := s.analyst
+	go func() {
+		a, err := analyst.search(&pos, analysisLimits)
+		s.mu.Lock()
+		s.analyzing = false
+		s.mu.Unlock()
+		b := protocol.NewBuilder()
+		if err != nil {
+			b = b.AddError(err.Error())
+		} else {
+			b = b.AddAnalysis(a.depth, a.score, a.mate, a.pv)
+		}
+		if err := s.write([]protocol.Builder{b}); err != nil {
+			s.logger.Errorf("failed to send analysis: %v", err)
+		}
+	}()
+	return nil
+}
+
+func (s *session) computerToMove() bool {
+	return s.computer != nil && !s.g.Status().IsOver() && s.g.ToMove() == s.computer.color
+}
+
+// changed marks the game as changed by the client,
+// cutting short anything the computer was thinking about
+func (s *session) changed() {
+	s.version++
+	if s.stopSearch != nil {
+		close(s.stopSearch)
+		s.stopSearch = nil
+	}
+}
+
+// think plays the computer's moves for as long as it is its turn.
+// only one think runs at a time, guarded by s.thinking
+func (s *session) think() {
+	for {
+		s.mu.Lock()
+		if !s.computerToMove() {
+			s.thinking = false
+			s.mu.Unlock()
+			return
+		}
+		pos := s.g.Clone()
+		version := s.version
+		cpu := s.computer
+		limits := cpu.limits
+		if s.clock != nil {
+			t := thinkingTime(s.clock.Remaining(cpu.color, time.Now()))
+			if limits.Time == 0 || t < limits.Time {
+				limits.Time = t
+			}
+		}
+		stop := make(chan struct{})
+		s.stopSearch = stop
+		s.mu.Unlock()
+
+		limits.Stop = stop
+		res, err := cpu.player.search(&pos, limits)
+
+		s.mu.Lock()
+		if s.stopSearch == stop {
+			s.stopSearch = nil
+		}
+		if err != nil && s.computer != cpu {
+			// the computer was replaced or the session closed
+			// while it was searching, so there is nobody to tell
+			s.mu.Unlock()
+			continue
+		}
+		if err != nil {
+			bufs := s.computerFailed(err)
+			s.writeMu.Lock()
+			s.mu.Unlock()
+			err = s.writeLocked(bufs)
+			s.writeMu.Unlock()
+			if err != nil {
+				s.logger.Errorf("failed to send computer error: %v", err)
+			}
+			continue
+		}
+		if version != s.version {
+			s.mu.Unlock()
+			continue
+		}
+		bufs := s.playComputerMove(res.move)
+		// take the socket before letting go of the game, or a
+		// resume in between would be sent this move twice
+		s.writeMu.Lock()
+		s.mu.Unlock()
+		err = s.writeLocked(bufs)
+		s.writeMu.Unlock()
+		if err != nil {
+			s.logger.Errorf("failed to send computer move: %v", err)
+		}
+	}
+}
+
+// computerFailed replaces a computer whose search failed, such as an
+// external engine that exited or stopped answering, with the built in
+// engine. if the built in engine is what failed the computer is taken
+// out of the game and the client plays both sides
+func (s *session) computerFailed(err error) []protocol.Builder {
+	cpu := s.computer
+	if _, ok := cpu.player.(*builtin); ok {
+		s.setComputer(nil)
+		return []protocol.Builder{
+			protocol.NewBuilder().AddError(fmt.Sprintf("the computer failed to move: %v, both sides are yours now", err)),
+		}
+	}
+	s.setComputer(&computer{
+		color:  cpu.color,
+		player: newBuiltin(),
+		limits: difficulties[defaultDifficulty-1],
+	})
+	return []protocol.Builder{
+		protocol.NewBuilder().AddError(fmt.Sprintf("the engine failed to move: %v, the built in engine plays on", err)),
+	}
+}
+
+func (s *session) playComputerMove(m game.Move) []protocol.Builder {
+	now := time.Now()
+	if s.outOfTime(now) {
+		return []protocol.Builder{
+			addClock(protocol.NewBuilder(), s.clock, now),
+			protocol.NewBuilder().AddResult(s.g.Status()),
+		}
+	}
+	err := s.g.Play(m)
+	if err != nil {
+		return []protocol.Builder{protocol.NewBuilder().AddError(err.Error())}
+	}
+	s.pressClock(now)
+	res := []protocol.Builder{addPlayed(protocol.NewBuilder(), m.Data())}
+	if s.clock != nil {
+		res = append(res, addClock(protocol.NewBuilder(), s.clock, now))
+	}
+	if status := s.g.Status(); status.IsOver() {
+		res = append(res, protocol.NewBuilder().AddResult(status))
+	}
+	return res
+}
+
+// outOfTime ends the game if the side to move has run out of time
+func (s *session) outOfTime(now time.Time) bool {
+	if !flagFall(&s.g, s.clock, now) {
+		return false
+	}
+	s.changed()
+	s.scheduleFlag()
+	return true
+}
+
+// pressClock ends the turn of whoever just moved
+func (s *session) pressClock(now time.Time) {
+	pressClock(&s.g, s.clock, now)
+	s.scheduleFlag()
+}
+
+// scheduleFlag sets flagTimer for when the side to move runs out of
+// time, so the game ends then even if nobody sends anything
+func (s *session) scheduleFlag() {
+	if s.flagTimer != nil {
+		s.flagTimer.Stop()
+		s.flagTimer = nil
+	}
+	if s.clock == nil || !s.clock.Running() || s.g.Status().IsOver() {
+		return
+	}
+	s.flagTimer = time.AfterFunc(time.Until(s.clock.Deadline()), s.timeUp)
+}
+
+// timeUp runs on flagTimer and sends the result if the side to move
+// has flagged. a timer that fired after a move finds them still in time
+func (s *session) timeUp() {
+	s.mu.Lock()
+	now := time.Now()
+	if !s.outOfTime(now) {
+		s.scheduleFlag()
+		s.mu.Unlock()
+		return
+	}
+	bufs := []protocol.Builder{
+		addClock(protocol.NewBuilder(), s.clock, now),
+		protocol.NewBuilder().AddResult(s.g.Status()),
+	}
+	s.writeMu.Lock()
+	s.mu.Unlock()
+	err := s.writeLocked(bufs)
+	s.writeMu.Unlock()
+	if err != nil {
+		s.logger.Errorf("failed to send flag fall: %v", err)
+	}
+}
+
+// addPlayed adds a move that was played to b
+func addPlayed(b protocol.Builder, data types.Data) protocol.Builder {
+	switch data.Type {
+	case types.MoveType:
+		move := data.Data.(types.Move)
+		b = b.AddMove(&move)
+		break
+	case types.PromotionType:
+		promotion := data.Data.(types.Promotion)
+		b = b.AddPromotion(&promotion)
+		break
+	}
+	return b
+}
+
+func (s *session) write(bufs []protocol.Builder) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.writeLocked(bufs)
+}
+
+// writeLocked writes with writeMu already held. while the
+// socket is away there is nowhere to write, and the client
+// catches up on what it missed when it resumes
+func (s *session) writeLocked(bufs []protocol.Builder) error {
+	if s.ws == nil {
+		return nil
+	}
+	for _, buf := range bufs {
+		err := s.ws.WriteMessage(websocket.TextMessage, buf)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// close stops the computer once the socket has gone
+// away for good
+func (s *session) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	s.setComputer(nil)
+	if s.flagTimer != nil {
+		s.flagTimer.Stop()
+		s.flagTimer = nil
+	}
+	if s.analyst != nil {
+		go s.analyst.close()
+		s.analyst = nil
+	}
+}
@@ -0,0 +1,333 @@
+package uci
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vincer2040/chess/internal/engine"
+	"github.com/vincer2040/chess/internal/game"
+	"github.com/vincer2040/chess/internal/types"
+)
+
+const (
+	name   = "chess"
+	author = "vincer2040"
+
+	defaultHash = 16
+	maxHash     = 1024
+)
+
+// server speaks the Universal Chess Interface. commands are read
+// one line at a time and a search runs on its own goroutine so
+// that stop and isready are answered while it thinks
+type server struct {
+	out   io.Writer
+	outMu sync.Mutex
+
+	engine *engine.Engine
+	g      game.Game
+
+	searching sync.WaitGroup
+	// closed by stop to end the running search
+	stopped chan struct{}
+}
+
+// Run reads uci commands from in until quit or the end
+// of the input, writing the replies to out
+func Run(in io.Reader, out io.Writer) error {
+	s := &server{
+		out:    out,
+		engine: engine.New(defaultHash),
+		g:      game.New(game.STARTING_POSITION),
+	}
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "uci":
+			s.send("id name %s", name)
+			s.send("id author %s", author)
+			s.send("option name Hash type spin default %d min 1 max %d", defaultHash, maxHash)
+			s.send("uciok")
+			break
+		case "isready":
+			s.send("readyok")
+			break
+		case "ucinewgame":
+			s.stop()
+			s.engine.Clear()
+			s.g = game.New(game.STARTING_POSITION)
+			break
+		case "setoption":
+			s.stop()
+			s.setOption(fields[1:])
+			break
+		case "position":
+			s.stop()
+			err := s.position(fields[1:])
+			if err != nil {
+				s.send("info string %s", err)
+			}
+			break
+		case "go":
+			s.stop()
+			s.goSearch(fields[1:])
+			break
+		case "stop":
+			s.stop()
+			break
+		case "quit":
+			s.stop()
+			return nil
+		default:
+			s.send("info string unknown command: %s", fields[0])
+			break
+		}
+	}
+	s.stop()
+	return scanner.Err()
+}
+
+func (s *server) send(format string, args ...any) {
+	s.outMu.Lock()
+	defer s.outMu.Unlock()
+	fmt.Fprintf(s.out, format+"\n", args...)
+}
+
+// stop ends the running search, if any, and waits
+// for it to send its best move
+func (s *server) stop() {
+	if s.stopped != nil {
+		close(s.stopped)
+		s.stopped = nil
+	}
+	s.searching.Wait()
+}
+
+// setOption handles `setoption name <id> [value <x>]`
+func (s *server) setOption(args []string) {
+	var id, value string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "name":
+			if i+1 < len(args) {
+				id = args[i+1]
+				i++
+			}
+			break
+		case "value":
+			if i+1 < len(args) {
+				value = args[i+1]
+				i++
+			}
+			break
+		}
+	}
+	switch strings.ToLower(id) {
+	case "hash":
+		mb, err := strconv.Atoi(value)
+		if err != nil || mb < 1 || mb > maxHash {
+			s.send("info string invalid hash size: %s", value)
+			break
+		}
+		s.engine = engine.New(mb)
+		break
+	default:
+		s.send("info string unknown option: %s", id)
+		break
+	}
+}
+
+// position handles `position [startpos | fen <fen>] [moves <move>...]`
+func (s *server) position(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("expected startpos or fen")
+	}
+	var fen string
+	rest := args[1:]
+	switch args[0] {
+	case "startpos":
+		fen = game.STARTING_POSITION
+		break
+	case "fen":
+		end := len(rest)
+		for i, arg := range rest {
+			if arg == "moves" {
+				end = i
+				break
+			}
+		}
+		fen = strings.Join(rest[:end], " ")
+		rest = rest[end:]
+		break
+	default:
+		return fmt.Errorf("expected startpos or fen, got %s", args[0])
+	}
+	g, err := game.Parse(fen)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 && rest[0] == "moves" {
+		for _, m := range rest[1:] {
+			err := playMove(&g, m)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	s.g = g
+	return nil
+}
+
+func playMove(g *game.Game, s string) error {
+	data, err := g.ParseMove(s)
+	if err != nil {
+		return err
+	}
+	switch data.Type {
+	case types.MoveType:
+		move := data.Data.(types.Move)
+		return g.MakeMove(&move)
+	case types.PromotionType:
+		promotion := data.Data.(types.Promotion)
+		return g.MakePromotion(&promotion)
+	}
+	return fmt.Errorf("invalid move: %s", s)
+}
+
+// goSearch handles `go` and starts searching on another goroutine
+func (s *server) goSearch(args []string) {
+	var limits engine.Limits
+	var wtime, btime, winc, binc, movesToGo int
+	infinite := false
+	for i := 0; i < len(args); i++ {
+		var value int
+		if i+1 < len(args) {
+			value, _ = strconv.Atoi(args[i+1])
+		}
+		switch args[i] {
+		case "depth":
+			limits.Depth = value
+			i++
+			break
+		case "nodes":
+			limits.Nodes = uint64(value)
+			i++
+			break
+		case "movetime":
+			limits.Time = time.Duration(value) * time.Millisecond
+			i++
+			break
+		case "wtime":
+			wtime = value
+			i++
+			break
+		case "btime":
+			btime = value
+			i++
+			break
+		case "winc":
+			winc = value
+			i++
+			break
+		case "binc":
+			binc = value
+			i++
+			break
+		case "movestogo":
+			movesToGo = value
+			i++
+			break
+		case "infinite":
+			infinite = true
+			break
+		}
+	}
+	if limits.Time == 0 && (wtime > 0 || btime > 0) {
+		remaining, inc := wtime, winc
+		if s.g.ToMove() == 'b' {
+			remaining, inc = btime, binc
+		}
+		limits.Time = timeForMove(remaining, inc, movesToGo)
+	}
+	if infinite {
+		limits = engine.Limits{Depth: engine.MaxDepth}
+	}
+	stopped := make(chan struct{})
+	s.stopped = stopped
+	limits.Stop = stopped
+
+	pos := s.g.Clone()
+	start := time.Now()
+	s.engine.OnDepth = func(res engine.Result) {
+		s.sendInfo(res, time.Since(start))
+	}
+	s.searching.Add(1)
+	go func() {
+		defer s.searching.Done()
+		res, err := s.engine.Search(&pos, limits)
+		// an infinite search only gives its move once told to stop
+		if infinite {
+			<-stopped
+		}
+		if err != nil {
+			s.send("bestmove 0000")
+			return
+		}
+		if len(res.PV) > 1 {
+			s.send("bestmove %s ponder %s", res.Move, res.PV[1])
+			return
+		}
+		s.send("bestmove %s", res.Move)
+	}()
+}
+
+// timeForMove shares out the clock, keeping
+// some back in case the game goes long
+func timeForMove(remaining, inc, movesToGo int) time.Duration {
+	if movesToGo <= 0 {
+		movesToGo = 30
+	}
+	ms := remaining/movesToGo + inc/2
+	if limit := remaining - 50; ms > limit {
+		ms = limit
+	}
+	if ms < 10 {
+		ms = 10
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+func (s *server) sendInfo(res engine.Result, elapsed time.Duration) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "info depth %d score %s nodes %d", res.Depth, scoreString(res.Score), res.Nodes)
+	ms := elapsed.Milliseconds()
+	if ms > 0 {
+		fmt.Fprintf(&sb, " nps %d", res.Nodes*1000/uint64(ms))
+	}
+	fmt.Fprintf(&sb, " time %d", ms)
+	if len(res.PV) > 0 {
+		sb.WriteString(" pv")
+		for _, m := range res.PV {
+			sb.WriteByte(' ')
+			sb.WriteString(m.String())
+		}
+	}
+	s.send("%s", sb.String())
+}
+
+// scoreString gives mates in moves rather than as a raw score
+func scoreString(score int) string {
+	if moves, ok := engine.MateIn(score); ok {
+		return fmt.Sprintf("mate %d", moves)
+	}
+	return fmt.Sprintf("cp %d", score)
+}
@@ -0,0 +1,76 @@
+package uci
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func run(t *testing.T, input string) []string {
+	var out bytes.Buffer
+	err := Run(strings.NewReader(input), &out)
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	return strings.Split(strings.TrimSpace(out.String()), "\n")
+}
+
+func TestHandshake(t *testing.T) {
+	lines := run(t, "uci\nisready\nquit\n")
+	if lines[0] != "id name chess" {
+		t.Errorf("expected the engine name first, got %q", lines[0])
+	}
+	if lines[len(lines)-2] != "uciok" || lines[len(lines)-1] != "readyok" {
+		t.Errorf("expected uciok then readyok, got %q", lines)
+	}
+}
+
+func TestGoFindsMate(t *testing.T) {
+	lines := run(t, "position fen 6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1\ngo depth 3\n")
+	last := lines[len(lines)-1]
+	if last != "bestmove a1a8" {
+		t.Errorf("expected bestmove a1a8, got %q", last)
+	}
+	found := false
+	for _, line := range lines {
+		if strings.HasPrefix(line, "info depth") && strings.Contains(line, "score mate 1") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected an info line with score mate 1, got %q", lines)
+	}
+}
+
+func TestPositionMoves(t *testing.T) {
+	// after 1. f3 e5 2. g4 black mates with Qh4
+	lines := run(t, "position startpos moves f2f3 e7e5 g2g4\ngo depth 2\n")
+	last := lines[len(lines)-1]
+	if !strings.HasPrefix(last, "bestmove d8h4") {
+		t.Errorf("expected bestmove d8h4, got %q", last)
+	}
+}
+
+func TestStopInfinite(t *testing.T) {
+	lines := run(t, "position startpos\ngo infinite\nstop\n")
+	last := lines[len(lines)-1]
+	if !strings.HasPrefix(last, "bestmove ") {
+		t.Errorf("expected a best move after stop, got %q", last)
+	}
+}
+
+func TestBadPosition(t *testing.T) {
+	lines := run(t, "position startpos moves e2e5\n")
+	if !strings.HasPrefix(lines[0], "info string") {
+		t.Errorf("expected an error for an illegal move, got %q", lines)
+	}
+}
+
+func TestTimeForMove(t *testing.T) {
+	if got := timeForMove(60000, 1000, 0); got.Milliseconds() != 2500 {
+		t.Errorf("expected 2500ms, got %s", got)
+	}
+	if got := timeForMove(30, 0, 0); got.Milliseconds() != 10 {
+		t.Errorf("expected the 10ms minimum, got %s", got)
+	}
+}
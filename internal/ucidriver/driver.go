@@ -0,0 +1,372 @@
+package ucidriver
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vincer2040/chess/internal/engine"
+	"github.com/vincer2040/chess/internal/game"
+)
+
+const (
+	// how long an engine gets to answer uci and isready
+	handshakeTimeout = 10 * time.Second
+	quitTimeout      = 2 * time.Second
+)
+
+// how long past its time limit an engine gets to give a move.
+// it is a var so that tests don't have to wait as long
+var searchGrace = 5 * time.Second
+
+var (
+	ErrExited   = errors.New("engine exited")
+	ErrTimedOut = errors.New("timed out waiting for the engine")
+)
+
+// Engine is a UCI engine running as a subprocess
+type Engine struct {
+	Name   string
+	Author string
+
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	// every line the engine prints, closed when its output ends
+	lines chan string
+	// one conversation with the engine at a time
+	mu sync.Mutex
+}
+
+// Info is what the engine last reported about its search.
+// scores are from the side to move's point of view and Mate
+// is set instead of Score when a mate has been found
+type Info struct {
+	Depth int
+	Score int
+	Mate  int
+	Nodes uint64
+	PV    []string
+}
+
+type Result struct {
+	Move   game.Move
+	Ponder string
+	Info   Info
+}
+
+// Start launches the engine and waits for it to finish the
+// handshake, so it is ready for a search once Start returns
+func Start(path string, args ...string) (*Engine, error) {
+	cmd := exec.Command(path, args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	err = cmd.Start()
+	if err != nil {
+		return nil, err
+	}
+	e := &Engine{
+		cmd:   cmd,
+		stdin: stdin,
+		lines: make(chan string, 64),
+	}
+	go e.readLines(stdout)
+
+	err = e.handshake()
+	if err != nil {
+		e.Close()
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *Engine) readLines(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		e.lines <- scanner.Text()
+	}
+	close(e.lines)
+}
+
+func (e *Engine) handshake() error {
+	err := e.send("uci")
+	if err != nil {
+		return err
+	}
+	timeout := time.After(handshakeTimeout)
+	for {
+		line, _, err := e.readLine(timeout, nil)
+		if err != nil {
+			return err
+		}
+		if name, ok := strings.CutPrefix(line, "id name "); ok {
+			e.Name = name
+		} else if author, ok := strings.CutPrefix(line, "id author "); ok {
+			e.Author = author
+		} else if line == "uciok" {
+			break
+		}
+	}
+	return e.ready()
+}
+
+func (e *Engine) send(format string, args ...any) error {
+	_, err := fmt.Fprintf(e.stdin, format+"\n", args...)
+	return err
+}
+
+// readLine waits for the next line, giving up once timeout fires.
+// a nil timeout waits forever. stopped is set instead of a line
+// when stop is closed, so the caller can react to it
+func (e *Engine) readLine(timeout <-chan time.Time, stop <-chan struct{}) (line string, stopped bool, err error) {
+	select {
+	case line, ok := <-e.lines:
+		if !ok {
+			return "", false, ErrExited
+		}
+		return line, false, nil
+	case <-timeout:
+		return "", false, ErrTimedOut
+	case <-stop:
+		return "", true, nil
+	}
+}
+
+// ready waits until the engine has caught up with what it was sent
+func (e *Engine) ready() error {
+	err := e.send("isready")
+	if err != nil {
+		return err
+	}
+	timeout := time.After(handshakeTimeout)
+	for {
+		line, _, err := e.readLine(timeout, nil)
+		if err != nil {
+			return err
+		}
+		if line == "readyok" {
+			return nil
+		}
+	}
+}
+
+func (e *Engine) SetOption(name, value string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	err := e.send("setoption name %s value %s", name, value)
+	if err != nil {
+		return err
+	}
+	return e.ready()
+}
+
+func (e *Engine) NewGame() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	err := e.send("ucinewgame")
+	if err != nil {
+		return err
+	}
+	return e.ready()
+}
+
+// Search sends the engine the game so far and asks it for a move.
+// closing limits.Stop tells the engine to stop and give its move
+func (e *Engine) Search(g *game.Game, limits engine.Limits) (Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if g.Status().IsOver() {
+		return Result{}, errors.New("game is over")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("position fen ")
+	sb.WriteString(g.StartingFEN())
+	moves := g.TrackedMoves()
+	if len(moves) > 0 {
+		sb.WriteString(" moves")
+		for _, m := range moves {
+			sb.WriteByte(' ')
+			sb.WriteString(m.String())
+		}
+	}
+	err := e.send("%s", sb.String())
+	if err != nil {
+		return Result{}, err
+	}
+	err = e.send("%s", goCommand(limits))
+	if err != nil {
+		return Result{}, err
+	}
+
+	var timeout <-chan time.Time
+	if limits.Time > 0 {
+		timeout = time.After(limits.Time + searchGrace)
+	}
+	stop := limits.Stop
+	var res Result
+	for {
+		line, stopped, err := e.readLine(timeout, stop)
+		if err == ErrTimedOut {
+			e.abort()
+			return Result{}, err
+		}
+		if err != nil {
+			return Result{}, err
+		}
+		if stopped {
+			// only ask once, then wait for the move
+			stop = nil
+			timeout = time.After(searchGrace)
+			err = e.send("stop")
+			if err != nil {
+				return Result{}, err
+			}
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "info":
+			parseInfo(fields[1:], &res.Info)
+			break
+		case "bestmove":
+			if len(fields) < 2 {
+				return Result{}, errors.New("engine gave no move")
+			}
+			m, ok := findMove(g, fields[1])
+			if !ok {
+				return Result{}, fmt.Errorf("engine gave an illegal move: %s", fields[1])
+			}
+			res.Move = m
+			if len(fields) == 4 && fields[2] == "ponder" {
+				res.Ponder = fields[3]
+			}
+			return res, nil
+		}
+	}
+}
+
+// abort stops a search that ran over and reads up to its move,
+// so that it isn't taken as the answer to the next search. an
+// engine that doesn't answer is killed
+func (e *Engine) abort() {
+	if e.send("stop") == nil {
+		timeout := time.After(searchGrace)
+		for {
+			line, _, err := e.readLine(timeout, nil)
+			if err != nil {
+				break
+			}
+			if strings.HasPrefix(line, "bestmove") {
+				return
+			}
+		}
+	}
+	e.cmd.Process.Kill()
+}
+
+func goCommand(limits engine.Limits) string {
+	var sb strings.Builder
+	sb.WriteString("go")
+	if limits.Depth > 0 {
+		fmt.Fprintf(&sb, " depth %d", limits.Depth)
+	}
+	if limits.Nodes > 0 {
+		fmt.Fprintf(&sb, " nodes %d", limits.Nodes)
+	}
+	if limits.Time > 0 {
+		fmt.Fprintf(&sb, " movetime %d", limits.Time.Milliseconds())
+	}
+	if sb.Len() == len("go") {
+		if limits.Stop != nil {
+			sb.WriteString(" infinite")
+		} else {
+			fmt.Fprintf(&sb, " depth %d", engine.DefaultDepth)
+		}
+	}
+	return sb.String()
+}
+
+// parseInfo picks out the parts of an info line we use. an info
+// line without a score, such as currmove updates, leaves it alone
+func parseInfo(fields []string, info *Info) {
+	for i := 0; i < len(fields); i++ {
+		switch fields[i] {
+		case "depth":
+			if i+1 < len(fields) {
+				info.Depth, _ = strconv.Atoi(fields[i+1])
+				i++
+			}
+			break
+		case "nodes":
+			if i+1 < len(fields) {
+				info.Nodes, _ = strconv.ParseUint(fields[i+1], 10, 64)
+				i++
+			}
+			break
+		case "score":
+			if i+2 < len(fields) {
+				value, _ := strconv.Atoi(fields[i+2])
+				if fields[i+1] == "mate" {
+					info.Mate = value
+					info.Score = 0
+				} else {
+					info.Mate = 0
+					info.Score = value
+				}
+				i += 2
+			}
+			break
+		case "pv":
+			// the pv runs to the end of the line
+			info.PV = append([]string(nil), fields[i+1:]...)
+			return
+		case "string":
+			return
+		}
+	}
+}
+
+func findMove(g *game.Game, s string) (game.Move, bool) {
+	moves := g.Moves()
+	for i := 0; i < moves.Len(); i++ {
+		if moves.At(i).String() == s {
+			return moves.At(i), true
+		}
+	}
+	return 0, false
+}
+
+// Close asks the engine to quit, killing it if it doesn't.
+// a search that is running is left to finish first
+func (e *Engine) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.send("quit")
+	e.stdin.Close()
+	done := make(chan error, 1)
+	go func() {
+		done <- e.cmd.Wait()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(quitTimeout):
+		e.cmd.Process.Kill()
+		return <-done
+	}
+}
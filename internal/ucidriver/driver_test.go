@@ -0,0 +1,169 @@
+package ucidriver
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vincer2040/chess/internal/engine"
+	"github.com/vincer2040/chess/internal/game"
+)
+
+var standin string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "standin")
+	if err != nil {
+		panic(err)
+	}
+	standin = filepath.Join(dir, "standin")
+	out, err := exec.Command("go", "build", "-o", standin, "./testdata/standin").CombinedOutput()
+	if err != nil {
+		panic(string(out))
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func play(t *testing.T, g *game.Game, moves ...string) {
+	for _, s := range moves {
+		m, ok := findMove(g, s)
+		if !ok {
+			t.Fatalf("illegal move %s", s)
+		}
+		g.Play(m)
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	e, err := Start(standin)
+	if err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+	defer e.Close()
+	if e.Name != "standin" || e.Author != "vincer2040" {
+		t.Errorf("unexpected id: %q by %q", e.Name, e.Author)
+	}
+	if err := e.NewGame(); err != nil {
+		t.Errorf("new game failed: %v", err)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	e, err := Start(standin)
+	if err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+	defer e.Close()
+
+	g := game.New(game.STARTING_POSITION)
+	play(t, &g, "e2e4", "d7d5")
+	res, err := e.Search(&g, engine.Limits{Time: time.Second})
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	// the standin takes when it can, so it has to have
+	// been sent the moves played
+	if res.Move.String() != "e4d5" {
+		t.Errorf("expected e4d5, got %s", res.Move)
+	}
+	if res.Info.Depth != 1 || res.Info.Score != 12 || len(res.Info.PV) != 1 {
+		t.Errorf("unexpected info: %+v", res.Info)
+	}
+}
+
+func TestSearchBlankLine(t *testing.T) {
+	e, err := Start(standin)
+	if err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+	defer e.Close()
+	if err := e.SetOption("Slow", "true"); err != nil {
+		t.Fatalf("set option failed: %v", err)
+	}
+
+	g := game.New(game.STARTING_POSITION)
+	stop := make(chan struct{})
+	wait := 200 * time.Millisecond
+	time.AfterFunc(wait, func() { close(stop) })
+	start := time.Now()
+	_, err = e.Search(&g, engine.Limits{Stop: stop})
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	// the standin prints a blank line before waiting for stop
+	if elapsed := time.Since(start); elapsed < wait {
+		t.Errorf("search stopped early, after %s", elapsed)
+	}
+}
+
+func TestSearchTimeout(t *testing.T) {
+	defer func(grace time.Duration) { searchGrace = grace }(searchGrace)
+	searchGrace = 50 * time.Millisecond
+
+	e, err := Start(standin)
+	if err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+	defer e.Close()
+	if err := e.SetOption("Slow", "true"); err != nil {
+		t.Fatalf("set option failed: %v", err)
+	}
+
+	g := game.New(game.STARTING_POSITION)
+	play(t, &g, "e2e4", "d7d5")
+	_, err = e.Search(&g, engine.Limits{Time: 10 * time.Millisecond})
+	if err != ErrTimedOut {
+		t.Fatalf("expected the search to time out, got %v", err)
+	}
+
+	// the move the engine gave late must not answer this search
+	g = game.New(game.STARTING_POSITION)
+	stop := make(chan struct{})
+	close(stop)
+	res, err := e.Search(&g, engine.Limits{Stop: stop})
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if res.Move.String() == "e4d5" {
+		t.Errorf("got the move from the search that timed out")
+	}
+}
+
+func TestParseInfo(t *testing.T) {
+	var info Info
+	parseInfo([]string{"depth", "7", "seldepth", "9", "score", "mate", "-3", "nodes", "1234", "pv", "e2e4", "e7e5"}, &info)
+	if info.Depth != 7 || info.Mate != -3 || info.Nodes != 1234 || len(info.PV) != 2 {
+		t.Errorf("unexpected info: %+v", info)
+	}
+	parseInfo([]string{"depth", "8", "currmove", "g1f3"}, &info)
+	if info.Depth != 8 || info.Mate != -3 {
+		t.Errorf("a line without a score should keep the last one: %+v", info)
+	}
+}
+
+func TestGoCommand(t *testing.T) {
+	tests := []struct {
+		limits engine.Limits
+		cmd    string
+	}{
+		{engine.Limits{Depth: 4}, "go depth 4"},
+		{engine.Limits{Time: 1500 * time.Millisecond}, "go movetime 1500"},
+		{engine.Limits{Stop: make(chan struct{})}, "go infinite"},
+		{engine.Limits{}, "go depth 6"},
+	}
+	for _, tt := range tests {
+		if got := goCommand(tt.limits); got != tt.cmd {
+			t.Errorf("expected %q, got %q", tt.cmd, got)
+		}
+	}
+}
+
+func TestStartMissingEngine(t *testing.T) {
+	if _, err := Start(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Errorf("expected an error starting a missing engine")
+	}
+}
@@ -0,0 +1,94 @@
+// standin is a tiny UCI engine for testing the driver. it plays
+// a capture if it has one and otherwise the first legal move.
+// with the Slow option set, or STANDIN_SLOW in its environment, it
+// only gives its move once told to stop
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/vincer2040/chess/internal/game"
+)
+
+func main() {
+	g := game.New(game.STARTING_POSITION)
+	slow := os.Getenv("STANDIN_SLOW") != ""
+	// the move held back until stop in slow mode
+	pending := ""
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "uci":
+			fmt.Println("id name standin")
+			fmt.Println("id author vincer2040")
+			fmt.Println("uciok")
+		case "isready":
+			fmt.Println("readyok")
+		case "setoption":
+			if len(fields) == 5 && fields[2] == "Slow" {
+				slow = fields[4] == "true"
+			}
+		case "position":
+			g = position(fields[1:])
+		case "go":
+			moves := g.Moves()
+			if moves.Len() == 0 {
+				fmt.Println("bestmove 0000")
+				continue
+			}
+			move := moves.At(0)
+			for i := 0; i < moves.Len(); i++ {
+				if moves.At(i).IsCapture() {
+					move = moves.At(i)
+					break
+				}
+			}
+			fmt.Printf("info depth 1 score cp 12 nodes 1 pv %s\n", move)
+			if slow {
+				// a blank line shouldn't be taken for anything
+				fmt.Println()
+				pending = move.String()
+				continue
+			}
+			fmt.Printf("bestmove %s\n", move)
+		case "stop":
+			if pending != "" {
+				fmt.Printf("bestmove %s\n", pending)
+				pending = ""
+			}
+		case "quit":
+			return
+		}
+	}
+}
+
+func position(args []string) game.Game {
+	fen := game.STARTING_POSITION
+	i := 1
+	if len(args) > 0 && args[0] == "fen" {
+		for i < len(args) && args[i] != "moves" {
+			i++
+		}
+		fen = strings.Join(args[1:i], " ")
+	}
+	g := game.New(fen)
+	if i < len(args) && args[i] == "moves" {
+		for _, m := range args[i+1:] {
+			moves := g.Moves()
+			for j := 0; j < moves.Len(); j++ {
+				if moves.At(j).String() == m {
+					g.Play(moves.At(j))
+					break
+				}
+			}
+		}
+	}
+	return g
+}
@@ -45,11 +45,42 @@ export class Game {
     /** @type {Queue<ArrayBuffer>}*/
     #messageQueue;
 
+    /** @type {boolean} */
+    #inRoom;
+
+    /** @type {string} */
+    #url;
+
+    /** @type {string} */
+    #tokenKey;
+
+    /** @type {boolean} */
+    #resuming;
+
+    /** @type {number} */
+    #retryDelay;
+
+    /** @type {boolean} */
+    #connected;
+
+    /** @type {boolean} */
+    #givenUp;
+
+    /** @type {import("./types").Clock | null} */
+    #clock;
+
+    /** @type {number} */
+    #clockReceivedAt;
+
+    /** @type {number | undefined} */
+    #clockTimer;
+
     /**
      * @param {string} startingPosition
-     * @param {WebSocket} ws
+     * @param {string} url
+     * @param {boolean} inRoom
      */
-    constructor(startingPosition, ws) {
+    constructor(startingPosition, url, inRoom) {
         const split = startingPosition.split(" ");
         this.#position = split[0];
         this.#toMove = split[1];
@@ -61,24 +92,74 @@ export class Game {
         this.#board = /** @type {HTMLElement} */(document.getElementById("board"));
         this.#legalMoves = new Map();
         this.#attackingMoves = new Map();
-        this.#ws = ws;
+        this.#inRoom = inRoom;
+        this.#url = url;
+        this.#tokenKey = "token:" + url;
+        this.#resuming = false;
+        this.#retryDelay = 1000;
+        this.#connected = false;
+        this.#givenUp = false;
+        this.#clock = null;
+        this.#clockReceivedAt = 0;
+        this.#clockTimer = undefined;
+        Game.#instance = this;
+        this.#connect();
+    }
+
+    #connect() {
+        const token = sessionStorage.getItem(this.#tokenKey);
+        let url = this.#url;
+        if (this.#inRoom && token) {
+            // the room gives our seat back for its token
+            url += "?token=" + token;
+        }
+        this.#ws = new WebSocket(url);
         this.#ws.addEventListener("message", Game.#handleMessageCallback);
         this.#messageQueue = new Queue();
 
-        let s = new Builder().addCommand("START").getBuf();
-        this.#messageQueue.enque(s);
+        // pick the game back up if this tab was already playing one
+        this.#resuming = token !== null;
+        if (!this.#inRoom) {
+            let s = new Builder().addCommand(token ? "RESUME:" + token : "START").getBuf();
+            this.#messageQueue.enque(s);
+        }
         let lm = new Builder().addCommand("LEGAL_MOVES").getBuf();
         this.#messageQueue.enque(lm);
         let am = new Builder().addCommand("ATTACKING_MOVES").getBuf();
         this.#messageQueue.enque(am);
         this.#ws.addEventListener("open", () => {
+            this.#connected = true;
             const start = this.#messageQueue.deque();
             if (start === null) {
                 throw new Error("impossible");
             }
             this.#ws.send(start);
         });
-        Game.#instance = this;
+        this.#ws.addEventListener("close", () => {
+            // only a game that was being played is worth coming back to
+            if (!this.#connected || this.#givenUp) {
+                return;
+            }
+            setTimeout(() => this.#connect(), this.#retryDelay);
+            // wait longer each time the server can't be reached, up to 30s
+            this.#retryDelay = Math.min(this.#retryDelay * 2, 30000);
+        });
+    }
+
+    /**
+     * @param {string} fen
+     */
+    #setPosition(fen) {
+        const split = fen.split(" ");
+        this.#position = split[0];
+        this.#toMove = split[1];
+        for (let rank = 0; rank < 8; ++rank) {
+            const rankEl = this.#board.children.item(rank);
+            for (let file = 0; file < 8; ++file) {
+                rankEl?.children.item(file)?.replaceChildren();
+            }
+        }
+        this.drawBoard();
     }
 
     drawBoard() {
@@ -101,6 +182,76 @@ export class Game {
         }
     }
 
+    /**
+     * @param {import("./types").Result} result
+     */
+    #showResult(result) {
+        const status = document.getElementById("status");
+        if (status) {
+            status.textContent = result.result + " " + result.reason;
+        }
+    }
+
+    /**
+     * @param {import("./types").Clock} clock
+     */
+    #setClock(clock) {
+        this.#clock = clock;
+        this.#clockReceivedAt = Date.now();
+        clearInterval(this.#clockTimer);
+        this.#clockTimer = undefined;
+        if (clock.running !== "-") {
+            // the server only sends the clock when it changes, so count
+            // down the side to move here
+            this.#clockTimer = setInterval(() => this.#showClock(), 100);
+        }
+        this.#showClock();
+    }
+
+    #showClock() {
+        if (this.#clock === null) {
+            return;
+        }
+        let white = this.#clock.white;
+        let black = this.#clock.black;
+        const elapsed = Date.now() - this.#clockReceivedAt;
+        if (this.#clock.running === "w") {
+            white = Math.max(white - elapsed, 0);
+        } else if (this.#clock.running === "b") {
+            black = Math.max(black - elapsed, 0);
+        }
+        const whiteEl = document.getElementById("white-clock");
+        if (whiteEl) {
+            whiteEl.textContent = "white " + this.#formatClock(white);
+        }
+        const blackEl = document.getElementById("black-clock");
+        if (blackEl) {
+            blackEl.textContent = "black " + this.#formatClock(black);
+        }
+    }
+
+    /**
+     * @param {number} ms
+     * @returns {string}
+     */
+    #formatClock(ms) {
+        const seconds = Math.ceil(ms / 1000);
+        const minutes = Math.floor(seconds / 60);
+        return minutes + ":" + String(seconds % 60).padStart(2, "0");
+    }
+
+    /**
+     * @param {string} cmd
+     */
+    #handleCommand(cmd) {
+        if (cmd.startsWith("TOKEN:")) {
+            // kept for the tab, so a reload or a dropped socket can resume
+            sessionStorage.setItem(this.#tokenKey, cmd.slice("TOKEN:".length));
+        }
+        this.#resuming = false;
+        this.#retryDelay = 1000;
+    }
+
     /**
      * @param {Uint8Array} message
      */
@@ -108,6 +259,21 @@ export class Game {
         const data = new Parser(message).parse();
         switch (data.type) {
             case DataTypes.Command:
+                this.#handleCommand(/** @type {string} */(data.data));
+                break;
+            case DataTypes.Error:
+                if (this.#resuming) {
+                    // the seat is gone, so don't keep asking for it
+                    this.#resuming = false;
+                    sessionStorage.removeItem(this.#tokenKey);
+                    if (this.#inRoom) {
+                        this.#givenUp = true;
+                        break;
+                    }
+                    // a game of our own can just start over
+                    this.#ws.send(new Builder().addCommand("START").getBuf());
+                    return;
+                }
                 break;
             case DataTypes.LegalMoves:
                 this.#legalMoves = /** @type {import("./types").LegalMoves} */(data.data);
@@ -116,7 +282,26 @@ export class Game {
                 this.#attackingMoves = /** @type {import("./types").AttackingMoves} */(data.data);
                 this.#showAttackingMoves();
                 break
+            case DataTypes.Result:
+                this.#showResult(/** @type {import("./types").Result} */(data.data));
+                break
+            case DataTypes.Clock:
+                this.#setClock(/** @type {import("./types").Clock} */(data.data));
+                break
+            case DataTypes.Position:
+                this.#setPosition(/** @type {string} */(data.data));
+                break
             case DataTypes.Move:
+            case DataTypes.Promotion:
+                // the other player moved, so catch up with the room
+                if (this.#inRoom) {
+                    const p = new Builder().addCommand("POSITION").getBuf();
+                    this.#messageQueue.enque(p);
+                    const lm = new Builder().addCommand("LEGAL_MOVES").getBuf();
+                    this.#messageQueue.enque(lm);
+                    const am = new Builder().addCommand("ATTACKING_MOVES").getBuf();
+                    this.#messageQueue.enque(am);
+                }
                 break
         }
         const next = this.#messageQueue.deque();
@@ -3,9 +3,17 @@ const url = "http://localhost:8080";
 
 const startingPosition = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1";
 
-const ws = new WebSocket(url.replace("http", "ws") + "/game")
+const gameID = document.body.dataset.game;
 
-const game = new Game(startingPosition, ws);
+const watching = document.body.dataset.watch === "true";
+
+let path = "/game";
+if (gameID) {
+    path += "/" + gameID + (watching ? "/watch" : "");
+}
+
+// a room sends the game to us, so there is nothing to start
+const game = new Game(startingPosition, url.replace("http", "ws") + path, Boolean(gameID));
 
 game.drawBoard();
 
@@ -11,6 +11,8 @@ const LEGAL_MOVES = 126; // ~
 const ATTACKING_MOVES = 94; // ^
 const ARRAY_BYTE = 42; // *
 const PROMOTION_BYTE = 33; // !
+const RESULT_BYTE = 61; // =
+const CLOCK_BYTE = 124; // |
 const ZERO_BYTE = 48; // 0
 
 export class Parser {
@@ -37,7 +39,7 @@ export class Parser {
      * @returns {import("./types").DataFromServer}
      */
     parse() {
-        /** @type {import("./types").LegalMoves | import("./types").AttackingMoves | import("./types").Move | string | null} */
+        /** @type {import("./types").LegalMoves | import("./types").AttackingMoves | import("./types").Move | import("./types").Result | import("./types").Clock | string | null} */
         let data = null;
         /** @type {import("./types").DataType} */
         let type = DataTypes.Illegal;
@@ -84,6 +86,18 @@ export class Parser {
                     type = DataTypes.Promotion;
                 }
                 break
+            case RESULT_BYTE:
+                data = this.#parseResult();
+                if (data !== null) {
+                    type = DataTypes.Result;
+                }
+                break
+            case CLOCK_BYTE:
+                data = this.#parseClock();
+                if (data !== null) {
+                    type = DataTypes.Clock;
+                }
+                break
         }
         return { type, data };
     }
@@ -137,6 +151,54 @@ export class Parser {
         return { from: parseInt(from), to: parseInt(to), promoteTo };
     }
 
+    /**
+     * @returns {import("./types").Result | null}
+     */
+    #parseResult() {
+        this.#readByte();
+        let result = "";
+        let reason = "";
+        while (this.#byte !== SEPARATOR && this.#byte !== 0) {
+            result += String.fromCharCode(this.#byte);
+            this.#readByte();
+        }
+        this.#readByte();
+        // @ts-ignore:
+        while (this.#byte !== RET_CAR && this.#byte !== 0) {
+            reason += String.fromCharCode(this.#byte);
+            this.#readByte();
+        }
+        if (!this.#expectEnd()) {
+            return null;
+        }
+        return { result, reason };
+    }
+
+    /**
+     * @returns {import("./types").Clock | null}
+     */
+    #parseClock() {
+        this.#readByte();
+        let white = "";
+        let black = "";
+        while (this.#byte !== SEPARATOR && this.#byte !== 0) {
+            white += String.fromCharCode(this.#byte);
+            this.#readByte();
+        }
+        this.#readByte();
+        while (this.#byte !== SEPARATOR && this.#byte !== 0) {
+            black += String.fromCharCode(this.#byte);
+            this.#readByte();
+        }
+        this.#readByte();
+        const running = String.fromCharCode(this.#byte);
+        this.#readByte();
+        if (!this.#expectEnd()) {
+            return null;
+        }
+        return { white: parseInt(white), black: parseInt(black), running };
+    }
+
     /**
      * @returns {string | null}
      */
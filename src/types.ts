@@ -7,6 +7,17 @@ export type Promotion = Move & {
     promoteTo: string;
 }
 
+export type Result = {
+    result: string;
+    reason: string;
+}
+
+export type Clock = {
+    white: number;
+    black: number;
+    running: string;
+}
+
 export const DataTypes = {
     Illegal: "illegal",
     Position: "position",
@@ -16,6 +27,8 @@ export const DataTypes = {
     LegalMoves: "legal moves",
     AttackingMoves: "attacking moves",
     Promotion: "promotion",
+    Result: "result",
+    Clock: "clock",
 } as const;
 
 export type DataType = typeof DataTypes[keyof typeof DataTypes];
@@ -25,5 +38,5 @@ export type AttackingMoves = Map<number, number[][]>;
 
 export type DataFromServer = {
     type: DataType,
-    data: LegalMoves | AttackingMoves | string | Move | Promotion | null;
+    data: LegalMoves | AttackingMoves | string | Move | Promotion | Result | Clock | null;
 }